@@ -5,11 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"sync/atomic"
 
-	"github.com/google/uuid"
-
 	"github.com/ThinkInAIXYZ/go-mcp/pkg"
 	"github.com/ThinkInAIXYZ/go-mcp/protocol"
 )
@@ -75,11 +74,17 @@ func (client *Client) ListPrompts(ctx context.Context) (*protocol.ListPromptsRes
 	return &result, nil
 }
 
-func (client *Client) GetPrompt(ctx context.Context, request *protocol.GetPromptRequest) (*protocol.GetPromptResult, error) {
+func (client *Client) GetPrompt(ctx context.Context, request *protocol.GetPromptRequest, opts ...RequestOption) (*protocol.GetPromptResult, error) {
 	if client.serverCapabilities.Prompts == nil {
 		return nil, pkg.ErrServerNotSupport
 	}
 
+	meta, release := client.withProgressToken(newRequestOptions(opts), request.Meta)
+	defer release()
+	withMeta := *request
+	withMeta.Meta = meta
+	request = &withMeta
+
 	response, err := client.callServer(ctx, protocol.PromptsGet, request)
 	if err != nil {
 		return nil, err
@@ -127,11 +132,34 @@ func (client *Client) ListResourceTemplates(ctx context.Context) (*protocol.List
 	return &result, nil
 }
 
-func (client *Client) ReadResource(ctx context.Context, request *protocol.ReadResourceRequest) (*protocol.ReadResourceResult, error) {
+// ReadResource reads a resource. With WithResourceCache, results carrying a version are cached
+// and later reads of the same uri are conditional: while the server reports the version unchanged
+// the cached result is returned, it is shared between callers and must not be modified.
+func (client *Client) ReadResource(ctx context.Context, request *protocol.ReadResourceRequest, opts ...RequestOption) (*protocol.ReadResourceResult, error) {
 	if client.serverCapabilities.Resources == nil {
 		return nil, pkg.ErrServerNotSupport
 	}
 
+	_, ranged := protocol.GetResourceRange(request.Meta)
+	cacheable := client.resourceCache != nil && !ranged && request.GetIfNoneMatch() == ""
+
+	var cached *protocol.ReadResourceResult
+	if cacheable {
+		var ok bool
+		if cached, ok = client.resourceCache.get(request.URI); ok {
+			meta := make(map[string]interface{}, len(request.Meta)+1)
+			for k, v := range request.Meta {
+				meta[k] = v
+			}
+			meta[protocol.ResourceIfNoneMatchKey] = cached.GetVersion()
+			request = &protocol.ReadResourceRequest{Meta: meta, URI: request.URI}
+		}
+	}
+
+	meta, release := client.withProgressToken(newRequestOptions(opts), request.Meta)
+	defer release()
+	request = &protocol.ReadResourceRequest{Meta: meta, URI: request.URI}
+
 	response, err := client.callServer(ctx, protocol.ResourcesRead, request)
 	if err != nil {
 		return nil, err
@@ -141,9 +169,62 @@ func (client *Client) ReadResource(ctx context.Context, request *protocol.ReadRe
 	if err := pkg.JSONUnmarshal(response, &result); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
+
+	if cacheable {
+		switch {
+		case result.IsNotModified() && cached != nil:
+			return cached, nil
+		case result.GetVersion() != "":
+			client.resourceCache.put(request.URI, &result)
+		default:
+			client.resourceCache.remove(request.URI)
+		}
+	}
 	return &result, nil
 }
 
+// ReadResourceStream reads a resource served in ranges (see server.StreamResourceHandler) range after range
+// and writes the content to w, so that only one range is held in memory. Resources served whole are written at once.
+func (client *Client) ReadResourceStream(ctx context.Context, request *protocol.ReadResourceRequest, w io.Writer, opts ...RequestOption) error {
+	var offset int64
+	if requested, ok := protocol.GetResourceRange(request.Meta); ok {
+		offset = requested.Offset
+	}
+
+	for {
+		meta := make(map[string]interface{}, len(request.Meta)+1)
+		for k, v := range request.Meta {
+			meta[k] = v
+		}
+		meta[protocol.ResourceRangeKey] = &protocol.ResourceRange{Offset: offset}
+
+		result, err := client.ReadResource(ctx, &protocol.ReadResourceRequest{Meta: meta, URI: request.URI}, opts...)
+		if err != nil {
+			return err
+		}
+		for _, content := range result.Contents {
+			switch c := content.(type) {
+			case *protocol.TextResourceContents:
+				_, err = io.WriteString(w, c.Text)
+			case *protocol.BlobResourceContents:
+				_, err = w.Write(c.Blob)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		returned, ok := protocol.GetResourceRange(result.Meta)
+		if !ok || returned.EOF {
+			return nil
+		}
+		if returned.Length <= 0 {
+			return fmt.Errorf("read resource %s: empty range at offset %d before the end", request.URI, offset)
+		}
+		offset = returned.Offset + returned.Length
+	}
+}
+
 func (client *Client) SubscribeResourceChange(ctx context.Context, request *protocol.SubscribeRequest) (*protocol.SubscribeResult, error) {
 	if client.serverCapabilities.Resources == nil || !client.serverCapabilities.Resources.Subscribe {
 		return nil, pkg.ErrServerNotSupport
@@ -199,11 +280,17 @@ func (client *Client) ListTools(ctx context.Context) (*protocol.ListToolsResult,
 	return &result, nil
 }
 
-func (client *Client) CallTool(ctx context.Context, request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+func (client *Client) CallTool(ctx context.Context, request *protocol.CallToolRequest, opts ...RequestOption) (*protocol.CallToolResult, error) {
 	if client.serverCapabilities.Tools == nil {
 		return nil, pkg.ErrServerNotSupport
 	}
 
+	meta, release := client.withProgressToken(newRequestOptions(opts), request.Meta)
+	defer release()
+	withMeta := *request
+	withMeta.Meta = meta
+	request = &withMeta
+
 	response, err := client.callServer(ctx, protocol.ToolsCall, request)
 	if err != nil {
 		return nil, err
@@ -217,27 +304,26 @@ func (client *Client) CallTool(ctx context.Context, request *protocol.CallToolRe
 }
 
 // CallToolWithProgressChan progressCh Used to return the progress notification, chan will close in the method after the end of the function.
+// Sends to progressCh never block: while the reader is behind only the latest notification is kept for it.
+// Prefer CallTool with WithProgress.
 func (client *Client) CallToolWithProgressChan(ctx context.Context, request *protocol.CallToolRequest,
 	progressCh chan<- *protocol.ProgressNotification) (*protocol.CallToolResult, error) { //nolint:gofumpt
+	sender := pkg.NewLatestSender(progressCh)
+	defer sender.Close()
 
-	progressToken := uuid.NewString()
-	client.progressChanRW.Lock()
-	client.progressToken2notifyChan[progressToken] = progressCh
-	client.progressChanRW.Unlock()
-	defer func() {
-		client.progressChanRW.Lock()
-		defer client.progressChanRW.Unlock()
-
-		delete(client.progressToken2notifyChan, progressToken)
-		close(progressCh)
-	}()
+	return client.CallTool(ctx, request, WithProgress(sender.Send))
+}
 
-	if request.Meta == nil {
-		request.Meta = make(map[string]interface{})
+// SendProgressNotification reports the progress of the server request handled with ctx, eg. by a SamplingHandler
+// waiting for the user. It fails if the server asked for no progress.
+func (client *Client) SendProgressNotification(ctx context.Context, notify *protocol.ProgressNotification) error {
+	progressToken, err := getProgressTokenFromCtx(ctx)
+	if err != nil {
+		return err
 	}
-	request.Meta[protocol.ProgressTokenKey] = progressToken
+	notify.ProgressToken = progressToken
 
-	return client.CallTool(ctx, request)
+	return client.sendMsgWithNotification(ctx, protocol.NotificationProgress, notify)
 }
 
 func (client *Client) sendNotification4Initialized(ctx context.Context) error {
@@ -39,6 +39,16 @@ func WithInitTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithResourceCache keeps up to size versioned ReadResource results, see Client.ReadResource.
+// Entries are dropped when the server sends notifications/resources/updated for their uri.
+func WithResourceCache(size int) Option {
+	return func(s *Client) {
+		if size > 0 {
+			s.resourceCache = newResourceCache(size)
+		}
+	}
+}
+
 func WithLogger(logger pkg.Logger) Option {
 	return func(s *Client) {
 		s.logger = logger
@@ -50,13 +60,19 @@ type Client struct {
 
 	reqID2respChan cmap.ConcurrentMap[string, chan *protocol.JSONRPCResponse]
 
-	progressChanRW           sync.RWMutex
-	progressToken2notifyChan map[string]chan<- *protocol.ProgressNotification
+	// serverReqID2cancelFunc cancels the server requests in flight on notifications/cancelled
+	serverReqID2cancelFunc cmap.ConcurrentMap[string, context.CancelFunc]
+
+	progressChanRW         sync.RWMutex
+	progressToken2listener map[string]*progressListener
 
 	samplingHandler SamplingHandler
+	samplingPolicy  *samplingPolicy
 
 	notifyHandler NotifyHandler
 
+	resourceCache *resourceCache
+
 	requestID int64
 
 	ready            *pkg.AtomicBool
@@ -78,15 +94,16 @@ type Client struct {
 
 func NewClient(t transport.ClientTransport, opts ...Option) (*Client, error) {
 	client := &Client{
-		transport:                t,
-		reqID2respChan:           cmap.New[chan *protocol.JSONRPCResponse](),
-		progressToken2notifyChan: make(map[string]chan<- *protocol.ProgressNotification),
-		ready:                    pkg.NewAtomicBool(),
-		clientInfo:               &protocol.Implementation{},
-		clientCapabilities:       &protocol.ClientCapabilities{},
-		initTimeout:              time.Second * 30,
-		closed:                   make(chan struct{}),
-		logger:                   pkg.DefaultLogger,
+		transport:              t,
+		reqID2respChan:         cmap.New[chan *protocol.JSONRPCResponse](),
+		serverReqID2cancelFunc: cmap.New[context.CancelFunc](),
+		progressToken2listener: make(map[string]*progressListener),
+		ready:                  pkg.NewAtomicBool(),
+		clientInfo:             &protocol.Implementation{},
+		clientCapabilities:     &protocol.ClientCapabilities{},
+		initTimeout:            time.Second * 30,
+		closed:                 make(chan struct{}),
+		logger:                 pkg.DefaultLogger,
 	}
 	t.SetReceiver(transport.NewClientReceiver(client.receive, client.receiveInterrupt))
 
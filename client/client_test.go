@@ -8,9 +8,11 @@ import (
 	"io"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/ThinkInAIXYZ/go-mcp/pkg"
 	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"github.com/ThinkInAIXYZ/go-mcp/server"
 	"github.com/ThinkInAIXYZ/go-mcp/transport"
 )
 
@@ -313,3 +315,73 @@ func testClientInit(t *testing.T, in io.ReadWriteCloser, out io.ReadWriter, outS
 	<-ch
 	return client
 }
+
+func TestCallToolWithProgressChanSlowReader(t *testing.T) {
+	clientTransport, serverTransport := transport.NewInMemoryPair()
+
+	svr, err := server.NewServer(serverTransport)
+	if err != nil {
+		t.Fatalf("NewServer: %+v", err)
+	}
+	tool, err := protocol.NewTool("slow_progress", "slow_progress", struct{}{})
+	if err != nil {
+		t.Fatalf("NewTool: %+v", err)
+	}
+	svr.RegisterTool(tool, func(ctx context.Context, _ *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+		for i := 1; i <= 10; i++ {
+			if err := svr.SendProgressNotification(ctx, protocol.NewProgressNotification(float64(i), 10, "")); err != nil {
+				return nil, err
+			}
+		}
+		return &protocol.CallToolResult{Content: []protocol.Content{&protocol.TextContent{Type: "text", Text: "done"}}}, nil
+	})
+	go func() {
+		_ = svr.Run()
+	}()
+	defer func() {
+		_ = svr.Shutdown(context.Background())
+	}()
+
+	client, err := NewClient(clientTransport)
+	if err != nil {
+		t.Fatalf("NewClient: %+v", err)
+	}
+	defer client.Close()
+
+	// nobody reads progressCh, the call must return regardless
+	progressCh := make(chan *protocol.ProgressNotification)
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.CallToolWithProgressChan(context.Background(), protocol.NewCallToolRequest("slow_progress", nil), progressCh)
+		done <- err
+	}()
+
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatalf("CallToolWithProgressChan: %+v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("CallToolWithProgressChan blocked on a channel nobody reads")
+	}
+	if _, ok := <-progressCh; ok {
+		t.Errorf("progressCh not closed")
+	}
+}
+
+type nilSampling struct{}
+
+func (nilSampling) CreateMessage(context.Context, *protocol.CreateMessageRequest) (*protocol.CreateMessageResult, error) {
+	return nil, nil
+}
+
+func TestSamplingPolicyNilResult(t *testing.T) {
+	policy := &samplingPolicy{}
+	request := protocol.NewCreateMessageRequest(nil, 100)
+	if _, err := policy.createMessage(context.Background(), nilSampling{}, request); err == nil {
+		t.Fatalf("createMessage() of a nil result expected error")
+	}
+	if policy.usage.Requests != 0 || policy.reserved != 0 {
+		t.Errorf("usage = %+v, reserved = %d, want nothing accounted", policy.usage, policy.reserved)
+	}
+}
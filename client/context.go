@@ -0,0 +1,20 @@
+package client
+
+import (
+	"context"
+	"errors"
+)
+
+type progressTokenKey struct{}
+
+func setProgressTokenToCtx(ctx context.Context, progressToken interface{}) context.Context {
+	return context.WithValue(ctx, progressTokenKey{}, progressToken)
+}
+
+func getProgressTokenFromCtx(ctx context.Context) (interface{}, error) {
+	progressToken := ctx.Value(progressTokenKey{})
+	if progressToken == nil {
+		return "", errors.New("no progress token found")
+	}
+	return progressToken, nil
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"time"
 
 	"github.com/ThinkInAIXYZ/go-mcp/pkg"
 	"github.com/ThinkInAIXYZ/go-mcp/protocol"
@@ -24,6 +23,9 @@ func (client *Client) handleRequestWithCreateMessagesSampling(ctx context.Contex
 		return nil, err
 	}
 
+	if client.samplingPolicy != nil {
+		return client.samplingPolicy.createMessage(ctx, client.samplingHandler, request)
+	}
 	return client.samplingHandler.CreateMessage(ctx, request)
 }
 
@@ -64,31 +66,23 @@ func (client *Client) handleNotifyWithResourcesUpdated(ctx context.Context, rawP
 			return err
 		}
 	}
+	if client.resourceCache != nil {
+		client.resourceCache.remove(notify.URI)
+	}
 	return client.notifyHandler.ResourcesUpdated(ctx, notify)
 }
 
-func (client *Client) handleNotifyWithProgress(ctx context.Context, rawParams json.RawMessage) error {
-	notify := &protocol.ProgressNotification{}
-	if len(rawParams) > 0 {
-		if err := pkg.JSONUnmarshal(rawParams, notify); err != nil {
-			return err
-		}
+func (client *Client) handleNotifyWithCancelled(rawParams json.RawMessage) error {
+	var params protocol.CancelledNotification
+	if err := pkg.JSONUnmarshal(rawParams, &params); err != nil {
+		return err
 	}
-	client.progressChanRW.RLock()
-	defer client.progressChanRW.RUnlock()
 
-	ch, ok := client.progressToken2notifyChan[fmt.Sprint(notify.ProgressToken)]
+	cancel, ok := client.serverReqID2cancelFunc.Get(fmt.Sprint(params.RequestID))
 	if !ok {
-		return fmt.Errorf("progress token not found")
+		return nil
 	}
+	cancel()
 
-	ctx, cancel := context.WithTimeout(ctx, time.Second*1)
-	defer cancel()
-
-	select {
-	case ch <- notify:
-	case <-ctx.Done():
-		return ctx.Err()
-	}
 	return nil
 }
@@ -0,0 +1,175 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+)
+
+const defaultProgressBuffer = 16
+
+// RequestOption configures a single request, see WithProgress.
+type RequestOption func(*requestOptions)
+
+type requestOptions struct {
+	progress       func(*protocol.ProgressNotification)
+	progressBuffer int
+}
+
+func newRequestOptions(opts []RequestOption) *requestOptions {
+	o := &requestOptions{progressBuffer: defaultProgressBuffer}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithProgress asks the server for progress notifications of the request and calls callback with each one,
+// in order and never after the request method has returned. The callback runs on its own goroutine:
+// while it is busy notifications are buffered, see WithProgressBuffer.
+func WithProgress(callback func(*protocol.ProgressNotification)) RequestOption {
+	return func(o *requestOptions) {
+		o.progress = callback
+	}
+}
+
+// WithProgressBuffer sets how many notifications wait for a busy progress callback, 16 by default.
+// When the buffer is full the newest notification replaces the last buffered one, so a slow
+// callback skips intermediate updates but always sees the latest progress.
+func WithProgressBuffer(size int) RequestOption {
+	return func(o *requestOptions) {
+		if size > 0 {
+			o.progressBuffer = size
+		}
+	}
+}
+
+// progressListener delivers the progress notifications of one request to its callback
+type progressListener struct {
+	callback func(*protocol.ProgressNotification)
+	size     int
+
+	mu     sync.Mutex
+	queue  []*protocol.ProgressNotification
+	closed bool
+
+	signal   chan struct{}
+	finished chan struct{}
+}
+
+func newProgressListener(callback func(*protocol.ProgressNotification), size int) *progressListener {
+	l := &progressListener{
+		callback: callback,
+		size:     size,
+		signal:   make(chan struct{}, 1),
+		finished: make(chan struct{}),
+	}
+	go func() {
+		defer pkg.Recover()
+		defer close(l.finished)
+
+		l.run()
+	}()
+	return l
+}
+
+// push never blocks, a full queue coalesces into its last entry
+func (l *progressListener) push(notify *protocol.ProgressNotification) {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return
+	}
+	if len(l.queue) < l.size {
+		l.queue = append(l.queue, notify)
+	} else {
+		l.queue[len(l.queue)-1] = notify
+	}
+	l.mu.Unlock()
+
+	select {
+	case l.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (l *progressListener) run() {
+	for range l.signal {
+		for {
+			l.mu.Lock()
+			if len(l.queue) == 0 {
+				closed := l.closed
+				l.mu.Unlock()
+				if closed {
+					return
+				}
+				break
+			}
+			notify := l.queue[0]
+			l.queue = l.queue[1:]
+			l.mu.Unlock()
+
+			l.callback(notify)
+		}
+	}
+}
+
+// close delivers the buffered notifications and waits for the callback to return
+func (l *progressListener) close() {
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+	close(l.signal)
+	<-l.finished
+}
+
+// withProgressToken registers the progress callback of o, if any, and returns a copy of meta
+// carrying the progress token. release unregisters it once the response has arrived.
+func (client *Client) withProgressToken(o *requestOptions, meta map[string]interface{}) (map[string]interface{}, func()) {
+	if o.progress == nil {
+		return meta, func() {}
+	}
+
+	progressToken := uuid.NewString()
+	listener := newProgressListener(o.progress, o.progressBuffer)
+	client.progressChanRW.Lock()
+	client.progressToken2listener[progressToken] = listener
+	client.progressChanRW.Unlock()
+
+	withToken := make(map[string]interface{}, len(meta)+1)
+	for k, v := range meta {
+		withToken[k] = v
+	}
+	withToken[protocol.ProgressTokenKey] = progressToken
+
+	return withToken, func() {
+		client.progressChanRW.Lock()
+		delete(client.progressToken2listener, progressToken)
+		client.progressChanRW.Unlock()
+		listener.close()
+	}
+}
+
+func (client *Client) handleNotifyWithProgress(_ context.Context, rawParams json.RawMessage) error {
+	notify := &protocol.ProgressNotification{}
+	if len(rawParams) > 0 {
+		if err := pkg.JSONUnmarshal(rawParams, notify); err != nil {
+			return err
+		}
+	}
+	client.progressChanRW.RLock()
+	defer client.progressChanRW.RUnlock()
+
+	listener, ok := client.progressToken2listener[fmt.Sprint(notify.ProgressToken)]
+	if !ok {
+		return fmt.Errorf("progress token not found")
+	}
+	listener.push(notify)
+	return nil
+}
@@ -62,8 +62,15 @@ func (client *Client) receive(ctx context.Context, msg []byte) error {
 	if !req.IsValid() {
 		return pkg.ErrRequestInvalid
 	}
+
+	// registered before returning, so that a cancellation following the request finds it
+	ctx, cancel := context.WithCancel(ctx)
+	requestID := fmt.Sprint(req.ID)
+	client.serverReqID2cancelFunc.Set(requestID, cancel)
 	go func() {
 		defer pkg.Recover()
+		defer cancel()
+		defer client.serverReqID2cancelFunc.Remove(requestID)
 
 		if err := client.receiveRequest(ctx, req); err != nil {
 			req.RawParams = nil // simplified log
@@ -80,6 +87,10 @@ func (client *Client) receiveRequest(ctx context.Context, request *protocol.JSON
 		err    error
 	)
 
+	if r := gjson.GetBytes(request.RawParams, fmt.Sprintf("_meta.%s", protocol.ProgressTokenKey)); r.Exists() {
+		ctx = setProgressTokenToCtx(ctx, r.Value())
+	}
+
 	switch request.Method {
 	case protocol.Ping:
 		result, err = client.handleRequestWithPing()
@@ -91,6 +102,11 @@ func (client *Client) receiveRequest(ctx context.Context, request *protocol.JSON
 		err = fmt.Errorf("%w: method=%s", pkg.ErrMethodNotSupport, request.Method)
 	}
 
+	if errors.Is(ctx.Err(), context.Canceled) {
+		// the server cancelled the request and expects no response
+		return nil
+	}
+
 	if err != nil {
 		switch {
 		case errors.Is(err, pkg.ErrMethodNotSupport):
@@ -99,6 +115,10 @@ func (client *Client) receiveRequest(ctx context.Context, request *protocol.JSON
 			return client.sendMsgWithError(ctx, request.ID, protocol.InvalidRequest, err.Error())
 		case errors.Is(err, pkg.ErrJSONUnmarshal):
 			return client.sendMsgWithError(ctx, request.ID, protocol.ParseError, err.Error())
+		case errors.Is(err, pkg.ErrSamplingPolicyViolation):
+			return client.sendMsgWithError(ctx, request.ID, protocol.InvalidParams, err.Error())
+		case errors.Is(err, pkg.ErrSamplingRejected):
+			return client.sendMsgWithError(ctx, request.ID, protocol.SamplingRejected, err.Error())
 		default:
 			return client.sendMsgWithError(ctx, request.ID, protocol.InternalError, err.Error())
 		}
@@ -118,6 +138,8 @@ func (client *Client) receiveNotify(ctx context.Context, notify *protocol.JSONRP
 		return client.handleNotifyWithResourcesUpdated(ctx, notify.RawParams)
 	case protocol.NotificationProgress:
 		return client.handleNotifyWithProgress(ctx, notify.RawParams)
+	case protocol.NotificationCancelled:
+		return client.handleNotifyWithCancelled(notify.RawParams)
 	default:
 		return fmt.Errorf("%w: method=%s", pkg.ErrMethodNotSupport, notify.Method)
 	}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+)
+
+// resourceCache is a LRU cache of read results keyed by uri
+type resourceCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type resourceCacheEntry struct {
+	uri    string
+	result *protocol.ReadResourceResult
+}
+
+func newResourceCache(capacity int) *resourceCache {
+	return &resourceCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element, capacity),
+	}
+}
+
+func (c *resourceCache) get(uri string) (*protocol.ReadResourceResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[uri]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*resourceCacheEntry).result, true
+}
+
+func (c *resourceCache) put(uri string, result *protocol.ReadResourceResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[uri]; ok {
+		elem.Value.(*resourceCacheEntry).result = result
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[uri] = c.order.PushFront(&resourceCacheEntry{uri: uri, result: result})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*resourceCacheEntry).uri)
+	}
+}
+
+func (c *resourceCache) remove(uri string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[uri]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, uri)
+	}
+}
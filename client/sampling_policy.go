@@ -0,0 +1,221 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+)
+
+// SamplingApprovalFunc asks the user about a sampling request before it reaches the SamplingHandler.
+// It returns the request to send, possibly edited, or nil to reject it. A returned error also rejects
+// the request, the server receives its message with the protocol.SamplingRejected code.
+type SamplingApprovalFunc func(ctx context.Context, request *protocol.CreateMessageRequest) (*protocol.CreateMessageRequest, error)
+
+type SamplingPolicyOption func(*samplingPolicy)
+
+// WithMaxTokensPerRequest lowers the maxTokens of a request to at most maxTokens.
+func WithMaxTokensPerRequest(maxTokens int) SamplingPolicyOption {
+	return func(p *samplingPolicy) {
+		p.maxTokensPerRequest = maxTokens
+	}
+}
+
+// WithMaxTokensPerSession limits the tokens granted to the server over the life of the client,
+// requests are lowered to the remaining budget and rejected once it is spent.
+func WithMaxTokensPerSession(maxTokens int) SamplingPolicyOption {
+	return func(p *samplingPolicy) {
+		p.maxTokensPerSession = maxTokens
+	}
+}
+
+// WithAllowedModelHints drops the model hints of requests that are not listed.
+func WithAllowedModelHints(names ...string) SamplingPolicyOption {
+	return func(p *samplingPolicy) {
+		p.allowedModelHints = make(map[string]bool, len(names))
+		for _, name := range names {
+			p.allowedModelHints[name] = true
+		}
+	}
+}
+
+// WithAllowedIncludeContext rejects requests asking for an includeContext value that is not listed,
+// eg. "allServers" when only "none" and "thisServer" are allowed. A request without includeContext is always allowed.
+func WithAllowedIncludeContext(values ...string) SamplingPolicyOption {
+	return func(p *samplingPolicy) {
+		p.allowedIncludeContext = make(map[string]bool, len(values))
+		for _, value := range values {
+			p.allowedIncludeContext[value] = true
+		}
+	}
+}
+
+// WithSamplingRewrite changes requests before the policy applies, eg. to add a system prompt.
+func WithSamplingRewrite(rewrite func(ctx context.Context, request *protocol.CreateMessageRequest) error) SamplingPolicyOption {
+	return func(p *samplingPolicy) {
+		p.rewrite = rewrite
+	}
+}
+
+// WithSamplingApproval sets the callback approving requests, it sees them after the rewrite and the limits.
+// Edited requests are checked against the limits again.
+func WithSamplingApproval(approve SamplingApprovalFunc) SamplingPolicyOption {
+	return func(p *samplingPolicy) {
+		p.approve = approve
+	}
+}
+
+// WithSamplingPolicy guards the SamplingHandler with limits on what servers may request, see SamplingPolicyOption.
+// Requests violating the policy are answered with an InvalidParams error, see Client.SamplingUsage for the accounting.
+func WithSamplingPolicy(opts ...SamplingPolicyOption) Option {
+	return func(s *Client) {
+		s.samplingPolicy = &samplingPolicy{}
+		for _, opt := range opts {
+			opt(s.samplingPolicy)
+		}
+	}
+}
+
+// SamplingUsage accounts for the sampling requests of the server. Tokens are counted as the maxTokens
+// granted to each request, an upper bound of what the model produced.
+type SamplingUsage struct {
+	Requests int
+	Rejected int
+	Tokens   int
+	// Models counts the requests answered by each model
+	Models map[string]int
+}
+
+type samplingPolicy struct {
+	maxTokensPerRequest   int
+	maxTokensPerSession   int
+	allowedModelHints     map[string]bool
+	allowedIncludeContext map[string]bool
+	rewrite               func(ctx context.Context, request *protocol.CreateMessageRequest) error
+	approve               SamplingApprovalFunc
+
+	mu    sync.Mutex
+	usage SamplingUsage
+	// reserved are the tokens granted to requests in flight
+	reserved int
+}
+
+// SamplingUsage returns the sampling accounting so far, it is empty without WithSamplingPolicy.
+func (client *Client) SamplingUsage() SamplingUsage {
+	if client.samplingPolicy == nil {
+		return SamplingUsage{}
+	}
+	return client.samplingPolicy.snapshot()
+}
+
+func (p *samplingPolicy) snapshot() SamplingUsage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	usage := p.usage
+	usage.Models = make(map[string]int, len(p.usage.Models))
+	for model, n := range p.usage.Models {
+		usage.Models[model] = n
+	}
+	return usage
+}
+
+func (p *samplingPolicy) createMessage(ctx context.Context, handler SamplingHandler, request *protocol.CreateMessageRequest) (*protocol.CreateMessageResult, error) {
+	granted, err := p.admit(ctx, request)
+	if err != nil {
+		p.mu.Lock()
+		p.usage.Rejected++
+		p.mu.Unlock()
+		return nil, err
+	}
+
+	result, err := handler.CreateMessage(ctx, granted)
+	if err == nil && result == nil {
+		err = errors.New("sampling handler returned no result")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reserved -= granted.MaxTokens
+	if err != nil {
+		return nil, err
+	}
+	p.usage.Requests++
+	p.usage.Tokens += granted.MaxTokens
+	if p.usage.Models == nil {
+		p.usage.Models = make(map[string]int)
+	}
+	p.usage.Models[result.Model]++
+	return result, nil
+}
+
+// admit applies the policy and the approval, reserving the granted tokens of the session budget
+func (p *samplingPolicy) admit(ctx context.Context, request *protocol.CreateMessageRequest) (*protocol.CreateMessageRequest, error) {
+	if p.rewrite != nil {
+		if err := p.rewrite(ctx, request); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.limit(request); err != nil {
+		return nil, err
+	}
+
+	if p.approve != nil {
+		approved, err := p.approve(ctx, request)
+		if err != nil {
+			if errors.Is(err, pkg.ErrSamplingRejected) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %v", pkg.ErrSamplingRejected, err)
+		}
+		if approved == nil {
+			return nil, fmt.Errorf("%w: by the user", pkg.ErrSamplingRejected)
+		}
+		if err = p.limit(approved); err != nil {
+			return nil, err
+		}
+		request = approved
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.maxTokensPerSession > 0 {
+		remaining := p.maxTokensPerSession - p.usage.Tokens - p.reserved
+		if remaining <= 0 {
+			return nil, fmt.Errorf("%w: the session token budget of %d is spent", pkg.ErrSamplingPolicyViolation, p.maxTokensPerSession)
+		}
+		if request.MaxTokens > remaining {
+			request.MaxTokens = remaining
+		}
+	}
+	p.reserved += request.MaxTokens
+	return request, nil
+}
+
+// limit applies the per request limits
+func (p *samplingPolicy) limit(request *protocol.CreateMessageRequest) error {
+	if request.MaxTokens <= 0 {
+		return fmt.Errorf("%w: maxTokens must be positive", pkg.ErrSamplingPolicyViolation)
+	}
+	if p.maxTokensPerRequest > 0 && request.MaxTokens > p.maxTokensPerRequest {
+		request.MaxTokens = p.maxTokensPerRequest
+	}
+
+	if p.allowedIncludeContext != nil && request.IncludeContext != "" && !p.allowedIncludeContext[request.IncludeContext] {
+		return fmt.Errorf("%w: includeContext %q is not allowed", pkg.ErrSamplingPolicyViolation, request.IncludeContext)
+	}
+
+	if p.allowedModelHints != nil && request.ModelPreferences != nil {
+		hints := make([]protocol.ModelHint, 0, len(request.ModelPreferences.Hints))
+		for _, hint := range request.ModelPreferences.Hints {
+			if p.allowedModelHints[hint.Name] {
+				hints = append(hints, hint)
+			}
+		}
+		request.ModelPreferences.Hints = hints
+	}
+	return nil
+}
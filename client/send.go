@@ -23,12 +23,16 @@ func (client *Client) sendMsgWithRequest(ctx context.Context, requestID protocol
 	}
 
 	if err = client.transport.Send(ctx, message); err != nil {
-		if !errors.Is(err, pkg.ErrSessionClosed) {
+		if !errors.Is(err, pkg.ErrSessionClosed) || method == protocol.Initialize {
 			return fmt.Errorf("sendRequest: transport send: %w", err)
 		}
+		// the server lost the session, eg. it was restarted: initialize a new one and send the request there
 		if err = client.againInitialization(ctx); err != nil {
 			return err
 		}
+		if err = client.transport.Send(ctx, message); err != nil {
+			return fmt.Errorf("sendRequest: transport send: %w", err)
+		}
 	}
 	return nil
 }
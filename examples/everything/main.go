@@ -86,7 +86,11 @@ func main() {
 		},
 		"required": ["file_name"]
 	}`)
-	tool4 := protocol.NewToolWithRawSchema("update_file", "update file", rawSchema)
+	tool4, err := protocol.NewToolWithSchema("update_file", "update file", protocol.RawSchema(rawSchema))
+	if err != nil {
+		log.Fatalf("Failed to create tool: %v", err)
+		return
+	}
 	limiter.SetToolLimit(tool4.Name, pkg.Rate{Limit: 1.0, Burst: 1})
 
 	testResource := &protocol.Resource{
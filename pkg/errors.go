@@ -18,6 +18,13 @@ var (
 	ErrSessionClosed             = errors.New("session closed")
 	ErrSendEOF                   = errors.New("send EOF")
 	ErrRateLimitExceeded         = errors.New("rate limit exceeded")
+	ErrResourceNotFound          = errors.New("resource not found")
+	ErrResourceTemplateConflict  = errors.New("resource template conflict")
+	ErrSamplingMaxIterations     = errors.New("sampling reached the maximum iterations")
+	ErrSamplingRejected          = errors.New("sampling request rejected")
+	ErrSamplingPolicyViolation   = errors.New("sampling request violates the policy")
+	ErrMessageTooLarge           = errors.New("message too large")
+	ErrJSONLimitExceeded         = errors.New("json limit exceeded")
 )
 
 type ResponseError struct {
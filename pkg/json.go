@@ -13,3 +13,59 @@ func JSONUnmarshal(data []byte, v interface{}) error {
 	}
 	return nil
 }
+
+// CheckJSONLimits reports with ErrJSONLimitExceeded a message nested deeper than maxDepth,
+// or a batch, a top-level array, longer than maxBatchLength. A limit of 0 is no limit.
+// The message is only scanned, its syntax is left to the decoder.
+func CheckJSONLimits(data []byte, maxDepth, maxBatchLength int) error {
+	var (
+		depth     int
+		batch     bool
+		elements  int
+		nextValue bool // a batch element begins at the next token
+		inString  bool
+		escaped   bool
+	)
+	for _, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+			continue
+		}
+
+		if batch && depth == 1 && c != ']' && c != ',' && nextValue {
+			nextValue = false
+			if elements++; maxBatchLength > 0 && elements > maxBatchLength {
+				return fmt.Errorf("%w: batch longer than %d", ErrJSONLimitExceeded, maxBatchLength)
+			}
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			if depth == 0 && c == '[' {
+				batch, nextValue = true, true
+			}
+			if depth++; maxDepth > 0 && depth > maxDepth {
+				return fmt.Errorf("%w: nesting deeper than %d", ErrJSONLimitExceeded, maxDepth)
+			}
+		case '}', ']':
+			depth--
+		case ',':
+			if batch && depth == 1 {
+				nextValue = true
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package pkg
+
+import "sync"
+
+// LatestSender passes values to a channel without ever blocking:
+// while the reader is behind only the latest value is kept for it.
+type LatestSender[T any] struct {
+	mu      sync.Mutex
+	ch      chan<- T
+	latest  T
+	pending bool
+	closed  bool
+}
+
+func NewLatestSender[T any](ch chan<- T) *LatestSender[T] {
+	return &LatestSender[T]{ch: ch}
+}
+
+// Send replaces the kept value, if any, and offers it to the channel
+func (s *LatestSender[T]) Send(v T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.latest, s.pending = v, true
+	s.trySend()
+}
+
+func (s *LatestSender[T]) trySend() {
+	select {
+	case s.ch <- s.latest:
+		var zero T
+		s.latest, s.pending = zero, false
+	default:
+	}
+}
+
+// Close offers the kept value one last time and closes the channel, later values are dropped
+func (s *LatestSender[T]) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	if s.pending {
+		s.trySend()
+	}
+	close(s.ch)
+}
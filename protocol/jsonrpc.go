@@ -18,6 +18,12 @@ const (
 
 	// 可以定义自己的错误代码，范围在-32000 以上。
 	ConnectionError = -32400
+
+	// ResourceNotFound is the MCP error code for an unknown resource uri
+	ResourceNotFound = -32002
+
+	// SamplingRejected is the MCP error code for a sampling request the user rejected
+	SamplingRejected = -1
 )
 
 type RequestID interface{} // 字符串/数值
@@ -37,8 +37,9 @@ type PromptArgument struct {
 
 // GetPromptRequest represents a request to get a specific prompt
 type GetPromptRequest struct {
-	Name      string            `json:"name"`
-	Arguments map[string]string `json:"arguments,omitempty"`
+	Meta      map[string]interface{} `json:"_meta,omitempty"`
+	Name      string                 `json:"name"`
+	Arguments map[string]string      `json:"arguments,omitempty"`
 }
 
 // GetPromptResult represents the response to a get prompt request
@@ -1,8 +1,12 @@
 package protocol
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/yosida95/uritemplate/v3"
 
@@ -37,13 +41,112 @@ type ListResourceTemplatesResult struct {
 
 // ReadResourceRequest represents a request to read a specific resource
 type ReadResourceRequest struct {
+	Meta      map[string]interface{} `json:"_meta,omitempty"`
 	URI       string                 `json:"uri"`
 	Arguments map[string]interface{} `json:"-"`
 }
 
 // ReadResourceResult The server's response to a resources/read request from the client.
 type ReadResourceResult struct {
-	Contents []ResourceContents `json:"contents"`
+	Meta     map[string]interface{} `json:"_meta,omitempty"`
+	Contents []ResourceContents     `json:"contents"`
+}
+
+// Keys of _meta used for conditional reads: a result carries the version of its content, a request
+// carries the version the client already holds, and a server that finds it unchanged answers
+// with notModified and no contents.
+const (
+	ResourceVersionKey     = "version"
+	ResourceIfNoneMatchKey = "ifNoneMatch"
+	ResourceNotModifiedKey = "notModified"
+)
+
+// NewNotModifiedReadResourceResult creates the answer to a conditional read whose version is still current
+func NewNotModifiedReadResourceResult(version string) *ReadResourceResult {
+	return &ReadResourceResult{
+		Meta:     map[string]interface{}{ResourceVersionKey: version, ResourceNotModifiedKey: true},
+		Contents: []ResourceContents{},
+	}
+}
+
+// SetVersion records the version of the contents, eg. a hash or modification counter
+func (r *ReadResourceResult) SetVersion(version string) {
+	if r.Meta == nil {
+		r.Meta = make(map[string]interface{})
+	}
+	r.Meta[ResourceVersionKey] = version
+}
+
+func (r *ReadResourceResult) GetVersion() string {
+	version, _ := r.Meta[ResourceVersionKey].(string)
+	return version
+}
+
+func (r *ReadResourceResult) IsNotModified() bool {
+	notModified, _ := r.Meta[ResourceNotModifiedKey].(bool)
+	return notModified
+}
+
+// GetIfNoneMatch returns the version the client already holds, if any
+func (r *ReadResourceRequest) GetIfNoneMatch() string {
+	version, _ := r.Meta[ResourceIfNoneMatchKey].(string)
+	return version
+}
+
+// ResourceContentsVersion hashes contents into a version usable with SetVersion
+func ResourceContentsVersion(contents []ResourceContents) string {
+	h := sha256.New()
+	for _, content := range contents {
+		switch c := content.(type) {
+		case *TextResourceContents:
+			fmt.Fprintf(h, "text:%d:%s:%d:%s:%d;", len(c.URI), c.URI, len(c.MimeType), c.MimeType, len(c.Text))
+			h.Write([]byte(c.Text))
+		case *BlobResourceContents:
+			fmt.Fprintf(h, "blob:%d:%s:%d:%s:%d;", len(c.URI), c.URI, len(c.MimeType), c.MimeType, len(c.Blob))
+			h.Write(c.Blob)
+		default:
+			b, _ := json.Marshal(content)
+			h.Write(b)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+const ResourceRangeKey = "range"
+
+// ResourceRange selects a byte range of a resource content, it is carried under _meta.range.
+// In a request Offset and Length ask for a range, the server may return less than Length.
+// In a result they describe the range returned, Total is the content size when known
+// and EOF tells that the range ends the content.
+type ResourceRange struct {
+	Offset int64 `json:"offset"`
+	Length int64 `json:"length,omitempty"`
+	Total  int64 `json:"total,omitempty"`
+	EOF    bool  `json:"eof,omitempty"`
+}
+
+// GetResourceRange reads the range from the _meta of a request or result.
+func GetResourceRange(meta map[string]interface{}) (*ResourceRange, bool) {
+	value, ok := meta[ResourceRangeKey]
+	if !ok {
+		return nil, false
+	}
+	switch r := value.(type) {
+	case *ResourceRange:
+		return r, r != nil
+	case ResourceRange:
+		return &r, true
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	r := &ResourceRange{}
+	if err = pkg.JSONUnmarshal(b, r); err != nil {
+		return nil, false
+	}
+	return r, true
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface for ReadResourceResult
@@ -108,8 +211,23 @@ type ResourceTemplate struct {
 	URITemplateParsed *uritemplate.Template `json:"-"`
 	Description       string                `json:"description,omitempty"`
 	MimeType          string                `json:"mimeType,omitempty"`
+	// VariableTypes declares how variables are passed in ReadResourceRequest.Arguments,
+	// variables not listed are TemplateVariableString
+	VariableTypes map[string]TemplateVariableType `json:"-"`
 }
 
+// TemplateVariableType is the type of a uri template variable in ReadResourceRequest.Arguments.
+type TemplateVariableType string
+
+const (
+	// TemplateVariableString passes a string, or a []string for list expansions such as {/path*}
+	TemplateVariableString TemplateVariableType = "string"
+	// TemplateVariableInt passes an int64, a uri whose value is not an integer does not match the template
+	TemplateVariableInt TemplateVariableType = "int"
+	// TemplateVariableList always passes a []string, a single value is split at commas
+	TemplateVariableList TemplateVariableType = "list"
+)
+
 func (t *ResourceTemplate) GetName() string {
 	return t.Name
 }
@@ -142,9 +260,83 @@ func (t *ResourceTemplate) ParseURITemplate() error {
 		return err
 	}
 	t.URITemplateParsed = template
+
+	varnames := make(map[string]bool)
+	for _, name := range template.Varnames() {
+		varnames[name] = true
+	}
+	for name, typ := range t.VariableTypes {
+		if !varnames[name] {
+			return fmt.Errorf("variable type declared for %s, which is not a variable of %s", name, t.URITemplate)
+		}
+		switch typ {
+		case TemplateVariableString, TemplateVariableInt, TemplateVariableList:
+		default:
+			return fmt.Errorf("unsupported type %q of variable %s", typ, name)
+		}
+	}
 	return nil
 }
 
+// MatchArguments extracts the variables of uri typed according to VariableTypes,
+// it reports false if uri does not match the template or a value does not fit its type.
+func (t *ResourceTemplate) MatchArguments(uri string) (map[string]interface{}, bool) {
+	if t.URITemplateParsed == nil || !t.URITemplateParsed.Regexp().MatchString(uri) {
+		return nil, false
+	}
+
+	exploded := explodedVariables(t.URITemplate)
+	arguments := make(map[string]interface{})
+	for name, value := range t.URITemplateParsed.Match(uri) {
+		list := value.List()
+		if list == nil && exploded[name] {
+			list = []string{value.String()}
+		}
+
+		switch t.VariableTypes[name] {
+		case TemplateVariableInt:
+			if list != nil {
+				return nil, false
+			}
+			n, err := strconv.ParseInt(value.String(), 10, 64)
+			if err != nil {
+				return nil, false
+			}
+			arguments[name] = n
+		case TemplateVariableList:
+			if list == nil {
+				list = strings.Split(value.String(), ",")
+			}
+			arguments[name] = list
+		default:
+			if list != nil {
+				arguments[name] = list
+			} else {
+				arguments[name] = value.String()
+			}
+		}
+	}
+	return arguments, true
+}
+
+// explodedVariables returns the variables with the explode modifier, eg. path of {/path*}
+func explodedVariables(uriTemplate string) map[string]bool {
+	exploded := make(map[string]bool)
+	for {
+		start := strings.IndexByte(uriTemplate, '{')
+		end := strings.IndexByte(uriTemplate, '}')
+		if start < 0 || end < start {
+			return exploded
+		}
+		for _, spec := range strings.Split(strings.TrimLeft(uriTemplate[start+1:end], "+#./;?&"), ",") {
+			if strings.HasSuffix(spec, "*") {
+				exploded[strings.TrimSuffix(spec, "*")] = true
+			}
+		}
+		uriTemplate = uriTemplate[end+1:]
+	}
+}
+
 func (t *ResourceTemplate) GetURITemplate() *uritemplate.Template {
 	return t.URITemplateParsed
 }
@@ -9,6 +9,7 @@ import (
 
 // CreateMessageRequest represents a request to create a message through sampling
 type CreateMessageRequest struct {
+	Meta             map[string]interface{} `json:"_meta,omitempty"`
 	Messages         []*SamplingMessage     `json:"messages"`
 	MaxTokens        int                    `json:"maxTokens"`
 	Temperature      float64                `json:"temperature,omitempty"`
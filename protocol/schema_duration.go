@@ -0,0 +1,153 @@
+package protocol
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isoDurationFormat is the format tag value of time.Duration fields taking ISO 8601 durations,
+// eg. Timeout time.Duration `json:"timeout" format:"duration"` accepts "PT1M30S" instead of nanoseconds.
+const isoDurationFormat = "duration"
+
+// isoDurationRegexp matches the ISO 8601 durations of RFC 3339 appendix A, a fraction is allowed in any component.
+var isoDurationRegexp = regexp.MustCompile(`^P(?:(\d+(?:[.,]\d+)?)Y)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)W)?(?:(\d+(?:[.,]\d+)?)D)?` +
+	`(?:T(?:(\d+(?:[.,]\d+)?)H)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)S)?)?$`)
+
+func checkISODuration(value string) error {
+	if !isoDurationRegexp.MatchString(value) || value == "P" || strings.HasSuffix(value, "T") {
+		return fmt.Errorf("expected ISO 8601 duration")
+	}
+	return nil
+}
+
+// parseISODuration converts an ISO 8601 duration such as "P1DT2H" into a time.Duration. A day is 24 hours,
+// years and months have no fixed length and are refused.
+func parseISODuration(value string) (time.Duration, error) {
+	if err := checkISODuration(value); err != nil {
+		return 0, err
+	}
+	match := isoDurationRegexp.FindStringSubmatch(value)
+	for _, component := range match[1:3] {
+		if component == "" {
+			continue
+		}
+		if n, _ := strconv.ParseFloat(strings.Replace(component, ",", ".", 1), 64); n != 0 {
+			return 0, fmt.Errorf("years and months have no fixed duration")
+		}
+	}
+
+	var total time.Duration
+	for i, unit := range []struct {
+		suffix string
+		scale  time.Duration
+	}{
+		{"h", 7 * 24}, // weeks
+		{"h", 24},     // days
+		{"h", 1},
+		{"m", 1},
+		{"s", 1},
+	} {
+		component := match[3+i]
+		if component == "" {
+			continue
+		}
+		d, err := time.ParseDuration(strings.Replace(component, ",", ".", 1) + unit.suffix)
+		if err != nil || d > math.MaxInt64/unit.scale {
+			return 0, fmt.Errorf("duration out of range")
+		}
+		d *= unit.scale
+		if total > math.MaxInt64-d {
+			return 0, fmt.Errorf("duration out of range")
+		}
+		total += d
+	}
+	return total, nil
+}
+
+// isISODurationField reports whether field is a time.Duration tagged to take ISO 8601 durations.
+func isISODurationField(field reflect.StructField) bool {
+	t := field.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == durationType && field.Tag.Get("format") == isoDurationFormat
+}
+
+// decodeISODurations replaces, in data decoded from JSON for a value of type t, the ISO 8601 durations
+// of the fields tagged format:"duration" with the nanoseconds encoding/json expects.
+// It reports whether data was changed.
+func decodeISODurations(t reflect.Type, data any, path string, errs *ValidationErrors) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	changed := false
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := data.(map[string]any)
+		if !ok || t == timeType {
+			return false
+		}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous {
+				// the fields of an embedded struct are properties of the same object
+				changed = decodeISODurations(field.Type, object, path, errs) || changed
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			value, ok := object[name]
+			if !ok {
+				continue
+			}
+			if !isISODurationField(field) {
+				changed = decodeISODurations(field.Type, value, joinPointer(path, name), errs) || changed
+				continue
+			}
+			text, ok := value.(string)
+			if !ok {
+				continue
+			}
+			d, err := parseISODuration(text)
+			if err != nil {
+				*errs = append(*errs, &ValidationError{Path: joinPointer(path, name), Keyword: "format",
+					Message: fmt.Sprintf("must be a valid %s: %v", isoDurationFormat, err)})
+				continue
+			}
+			object[name] = int64(d)
+			changed = true
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := data.([]any)
+		if !ok {
+			return false
+		}
+		for i, item := range items {
+			changed = decodeISODurations(t.Elem(), item, joinPointer(path, strconv.Itoa(i)), errs) || changed
+		}
+	case reflect.Map:
+		object, ok := data.(map[string]any)
+		if !ok {
+			return false
+		}
+		for key, value := range object {
+			changed = decodeISODurations(t.Elem(), value, joinPointer(path, key), errs) || changed
+		}
+	default:
+	}
+	return changed
+}
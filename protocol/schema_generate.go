@@ -1,10 +1,13 @@
 package protocol
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ThinkInAIXYZ/go-mcp/pkg"
 )
@@ -22,7 +25,9 @@ const (
 )
 
 type Property struct {
-	Type DataType `json:"type"`
+	Type DataType `json:"type,omitempty"`
+	// Nullable allows null in addition to Type, it is serialized as a type array such as ["string", "null"].
+	Nullable bool `json:"-"`
 	// Description is the description of the schema.
 	Description string `json:"description,omitempty"`
 	// Items specifies which data type an array contains, if the schema type is Array.
@@ -31,10 +36,181 @@ type Property struct {
 	Properties map[string]*Property `json:"properties,omitempty"`
 	Required   []string             `json:"required,omitempty"`
 	Enum       []string             `json:"enum,omitempty"`
+	// AdditionalProperties is either a bool or a *Property describing the properties not listed in Properties.
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+
+	Minimum          *float64 `json:"minimum,omitempty"`
+	Maximum          *float64 `json:"maximum,omitempty"`
+	ExclusiveMinimum *float64 `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum *float64 `json:"exclusiveMaximum,omitempty"`
+	MinLength        *int     `json:"minLength,omitempty"`
+	MaxLength        *int     `json:"maxLength,omitempty"`
+	MinItems         *int     `json:"minItems,omitempty"`
+	MaxItems         *int     `json:"maxItems,omitempty"`
+	Pattern          string   `json:"pattern,omitempty"`
+	// Format is checked for date-time, date, time, uri, email and uuid, other formats are annotations only.
+	Format string `json:"format,omitempty"`
+
+	Const    interface{}   `json:"const,omitempty"`
+	Default  interface{}   `json:"default,omitempty"`
+	Examples []interface{} `json:"examples,omitempty"`
+
+	OneOf []*Property `json:"oneOf,omitempty"`
+	AnyOf []*Property `json:"anyOf,omitempty"`
+	AllOf []*Property `json:"allOf,omitempty"`
+
+	// Ref points to another schema, e.g. "#/$defs/Node", which is resolved against Defs of the root schema.
+	Ref  string               `json:"$ref,omitempty"`
+	Defs map[string]*Property `json:"$defs,omitempty"`
+}
+
+func (p Property) MarshalJSON() ([]byte, error) {
+	type alias Property
+	temp := &struct {
+		Type interface{} `json:"type,omitempty"`
+		*alias
+	}{
+		alias: (*alias)(&p),
+	}
+
+	if p.Type != "" {
+		temp.Type = p.Type
+		if p.Nullable && p.Type != Null {
+			temp.Type = []DataType{p.Type, Null}
+		}
+	}
+	return json.Marshal(temp)
+}
+
+func (p *Property) UnmarshalJSON(data []byte) error {
+	type alias Property
+	temp := &struct {
+		Type                 json.RawMessage      `json:"type,omitempty"`
+		Enum                 []json.RawMessage    `json:"enum,omitempty"`
+		AdditionalProperties json.RawMessage      `json:"additionalProperties,omitempty"`
+		Definitions          map[string]*Property `json:"definitions,omitempty"`
+		*alias
+	}{
+		alias: (*alias)(p),
+	}
+
+	if err := pkg.JSONUnmarshal(data, temp); err != nil {
+		return err
+	}
+
+	if err := p.unmarshalType(temp.Type); err != nil {
+		return err
+	}
+
+	if temp.Enum != nil {
+		p.Enum = make([]string, 0, len(temp.Enum))
+		for _, raw := range temp.Enum {
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				s = string(bytes.TrimSpace(raw))
+			}
+			p.Enum = append(p.Enum, s)
+		}
+	}
+
+	if len(temp.AdditionalProperties) != 0 {
+		var allowed bool
+		if err := json.Unmarshal(temp.AdditionalProperties, &allowed); err == nil {
+			p.AdditionalProperties = allowed
+		} else {
+			var schema *Property
+			if err = pkg.JSONUnmarshal(temp.AdditionalProperties, &schema); err != nil {
+				return err
+			}
+			p.AdditionalProperties = schema
+		}
+	}
+
+	for name, def := range temp.Definitions {
+		if p.Defs == nil {
+			p.Defs = make(map[string]*Property, len(temp.Definitions))
+		}
+		if _, ok := p.Defs[name]; !ok {
+			p.Defs[name] = def
+		}
+	}
+	return nil
+}
+
+// unmarshalType accepts both `"type": "string"` and `"type": ["string", "null"]`,
+// a type array with more than one non-null type is rewritten as anyOf.
+func (p *Property) unmarshalType(raw json.RawMessage) error {
+	p.Type, p.Nullable = "", false
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var single DataType
+	if err := json.Unmarshal(raw, &single); err == nil {
+		p.Type = single
+		return nil
+	}
+
+	var types []DataType
+	if err := pkg.JSONUnmarshal(raw, &types); err != nil {
+		return err
+	}
+	nonNull := make([]DataType, 0, len(types))
+	for _, t := range types {
+		if t == Null {
+			p.Nullable = true
+			continue
+		}
+		nonNull = append(nonNull, t)
+	}
+	switch len(nonNull) {
+	case 0:
+		p.Type, p.Nullable = Null, false
+	case 1:
+		p.Type = nonNull[0]
+	default:
+		for _, t := range nonNull {
+			p.AnyOf = append(p.AnyOf, &Property{Type: t})
+		}
+	}
+	return nil
 }
 
 var schemaCache = pkg.SyncMap[*InputSchema]{}
 
+var schemaImplementations = pkg.SyncMap[[]reflect.Type]{}
+
+// RegisterSchemaImplementations opts the interface type I into schema generation, fields of type I
+// are described as a oneOf over the schemas of impls. Implementations must be named structs (or pointers
+// to them) and should be distinguishable by their required properties, otherwise more than one branch matches.
+// Decoding arguments into an interface field still requires the enclosing type to implement json.Unmarshaler.
+// eg:
+// protocol.RegisterSchemaImplementations[Filter](EqualFilter{}, AndFilter{})
+func RegisterSchemaImplementations[I any](impls ...I) error {
+	iface := reflect.TypeOf((*I)(nil)).Elem()
+	if iface.Kind() != reflect.Interface {
+		return fmt.Errorf("%v is not an interface type", iface)
+	}
+
+	types := make([]reflect.Type, 0, len(impls))
+	for _, impl := range impls {
+		t := reflect.TypeOf(impl)
+		if t == nil {
+			return fmt.Errorf("nil implementation of %v", iface)
+		}
+		elem := t
+		for elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct || elem.Name() == "" {
+			return fmt.Errorf("implementation %v of %v is not a named struct", t, iface)
+		}
+		types = append(types, t)
+	}
+	schemaImplementations.Store(getTypeUUID(iface), types)
+	return nil
+}
+
 func generateSchemaFromReqStruct(v any) (*InputSchema, error) {
 	t := reflect.TypeOf(v)
 	for t.Kind() != reflect.Struct {
@@ -51,18 +227,54 @@ func generateSchemaFromReqStruct(v any) (*InputSchema, error) {
 
 	schema := &InputSchema{Type: Object}
 
-	property, err := reflectSchemaByObject(t)
+	g := newSchemaGenerator(t)
+	property, err := g.reflectSchemaByObject(t)
 	if err != nil {
 		return nil, err
 	}
 
 	schema.Properties = property.Properties
 	schema.Required = property.Required
+	if len(g.defs) > 0 {
+		schema.Defs = g.defs
+	}
 
 	schemaCache.Store(typeUID, schema)
 	return schema, nil
 }
 
+// GenerateSchemaFromParams describes named parameters, eg. the parameters of a function, as an object schema.
+// Pointer parameters are optional, all others are required.
+func GenerateSchemaFromParams(names []string, types []reflect.Type) (*InputSchema, error) {
+	if len(names) != len(types) {
+		return nil, fmt.Errorf("got %d parameter names for %d parameters", len(names), len(types))
+	}
+
+	g := newSchemaGenerator(nil)
+	for _, t := range types {
+		g.countType(t)
+	}
+
+	schema := &InputSchema{Type: Object, Properties: make(map[string]*Property, len(names))}
+	for i, name := range names {
+		if _, ok := schema.Properties[name]; ok || name == "" {
+			return nil, fmt.Errorf("invalid or duplicate parameter name %q", name)
+		}
+		item, err := g.reflectSchemaByType(types[i])
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", name, err)
+		}
+		schema.Properties[name] = item
+		if types[i].Kind() != reflect.Ptr {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	if len(g.defs) > 0 {
+		schema.Defs = g.defs
+	}
+	return schema, nil
+}
+
 func getTypeUUID(t reflect.Type) string {
 	if t.PkgPath() != "" && t.Name() != "" {
 		return t.PkgPath() + "." + t.Name()
@@ -71,7 +283,115 @@ func getTypeUUID(t reflect.Type) string {
 	return t.String()
 }
 
-func reflectSchemaByObject(t reflect.Type) (*Property, error) {
+// schemaGenerator reflects a root struct into a schema. Named structs reached more than once,
+// either shared by several fields or through themselves, are described once under $defs and
+// referenced with $ref, the root itself is referenced as "#".
+type schemaGenerator struct {
+	root   reflect.Type
+	counts map[reflect.Type]int
+	names  map[reflect.Type]string
+	defs   map[string]*Property
+}
+
+func newSchemaGenerator(root reflect.Type) *schemaGenerator {
+	g := &schemaGenerator{
+		root:   root,
+		counts: make(map[reflect.Type]int),
+		names:  make(map[reflect.Type]string),
+		defs:   make(map[string]*Property),
+	}
+	if root != nil {
+		g.countType(root)
+	}
+	return g
+}
+
+// countType records how often each named struct is reached from the root,
+// the fields of a struct are only visited the first time so that recursive types terminate.
+func (g *schemaGenerator) countType(t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		g.countType(t.Elem())
+	case reflect.Interface:
+		impls, _ := schemaImplementations.Load(getTypeUUID(t))
+		for _, impl := range impls {
+			g.countType(impl)
+		}
+	case reflect.Struct:
+		if t == timeType {
+			return
+		}
+		if t.Name() != "" {
+			g.counts[t]++
+			if g.counts[t] > 1 {
+				return
+			}
+		}
+		g.countFields(t)
+	default:
+	}
+}
+
+func (g *schemaGenerator) countFields(t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		switch {
+		case field.Anonymous:
+			g.countFields(field.Type)
+		case !field.IsExported() || field.Tag.Get("json") == "-":
+		default:
+			g.countType(field.Type)
+		}
+	}
+}
+
+// reference returns a $ref to the definition of t, generating the definition on first use.
+func (g *schemaGenerator) reference(t reflect.Type) (*Property, error) {
+	if t == g.root {
+		return &Property{Ref: "#"}, nil
+	}
+
+	name, ok := g.names[t]
+	if !ok {
+		name = g.defName(t)
+		g.names[t] = name
+
+		// register the definition before reflecting it, so that recursive fields resolve to the same name
+		def := &Property{}
+		g.defs[name] = def
+		object, err := g.reflectSchemaByObject(t)
+		if err != nil {
+			return nil, err
+		}
+		*def = *object
+	}
+	return &Property{Ref: "#/$defs/" + name}, nil
+}
+
+// defName derives a unique $defs key from the type name, types of the same name from different
+// packages are told apart by a numeric suffix.
+func (g *schemaGenerator) defName(t reflect.Type) string {
+	base := strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || r == '.' || '0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, t.Name())
+
+	name := base
+	for i := 2; ; i++ {
+		if _, ok := g.defs[name]; !ok {
+			return name
+		}
+		name = base + strconv.Itoa(i)
+	}
+}
+
+func (g *schemaGenerator) reflectSchemaByObject(t reflect.Type) (*Property, error) {
 	var (
 		properties      = make(map[string]*Property)
 		requiredFields  = make([]string, 0)
@@ -103,12 +423,20 @@ func reflectSchemaByObject(t reflect.Type) (*Property, error) {
 			required = false
 		}
 
-		item, err := reflectSchemaByType(field.Type)
+		item, err := g.reflectSchemaByType(field.Type)
 		if err != nil {
 			return nil, err
 		}
+		if isISODurationField(field) {
+			// an ISO 8601 string instead of nanoseconds, see isoDurationFormat
+			item = &Property{Type: String}
+		}
 
 		if description := field.Tag.Get("description"); description != "" {
+			if item.Description != "" {
+				// keep the unit of a time.Duration
+				description += " (" + item.Description + ")"
+			}
 			item.Description = description
 		}
 		properties[jsonTag] = item
@@ -148,10 +476,14 @@ func reflectSchemaByObject(t reflect.Type) (*Property, error) {
 			}
 			item.Enum = enumValues
 		}
+
+		if err = applyConstraintTags(field, item); err != nil {
+			return nil, fmt.Errorf("invalid tag on field %v: %w", jsonTag, err)
+		}
 	}
 
 	for _, field := range anonymousFields {
-		object, err := reflectSchemaByObject(field.Type)
+		object, err := g.reflectSchemaByObject(field.Type)
 		if err != nil {
 			return nil, err
 		}
@@ -172,9 +504,180 @@ func reflectSchemaByObject(t reflect.Type) (*Property, error) {
 	return property, nil
 }
 
-func reflectSchemaByType(t reflect.Type) (*Property, error) {
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// IsObjectStruct reports whether t, past any pointers, is a struct whose schema is an object of its fields.
+// time.Time is a struct but has a string schema.
+func IsObjectStruct(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+// durationDescription documents the unit of the integers time.Duration fields are encoded as
+const durationDescription = "duration in nanoseconds"
+
+// applyConstraintTags reads the minimum, maximum, minLength, maxLength, minItems, maxItems,
+// pattern, format, default and examples tags of field into item.
+// A time.Duration tagged format:"duration" takes ISO 8601 durations, its default and examples too.
+// eg:
+// Age   int           `json:"age" minimum:"0" maximum:"150" default:"18"`
+// Email string        `json:"email" format:"email" examples:"[\"alice@example.com\", \"bob@example.com\"]"`
+// Code  string        `json:"code" pattern:"^[A-Z]{3}$" minLength:"3" maxLength:"3"`
+// Tags  []string      `json:"tags,omitempty" maxItems:"5"`
+// Wait  time.Duration `json:"wait" format:"duration" default:"PT30S"`
+func applyConstraintTags(field reflect.StructField, item *Property) error {
+	parseValue := func(v string) (interface{}, error) {
+		if isISODurationField(field) {
+			_, err := parseISODuration(v)
+			return v, err
+		}
+		return parseTagValue(field.Type, v)
+	}
+
+	for _, tag := range []struct {
+		name string
+		dst  **float64
+	}{
+		{"minimum", &item.Minimum},
+		{"maximum", &item.Maximum},
+		{"exclusiveMinimum", &item.ExclusiveMinimum},
+		{"exclusiveMaximum", &item.ExclusiveMaximum},
+	} {
+		if v := field.Tag.Get(tag.name); v != "" {
+			num, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return fmt.Errorf("%s %q is not a number", tag.name, v)
+			}
+			*tag.dst = &num
+		}
+	}
+
+	for _, tag := range []struct {
+		name string
+		dst  **int
+	}{
+		{"minLength", &item.MinLength},
+		{"maxLength", &item.MaxLength},
+		{"minItems", &item.MinItems},
+		{"maxItems", &item.MaxItems},
+	} {
+		if v := field.Tag.Get(tag.name); v != "" {
+			num, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil || num < 0 {
+				return fmt.Errorf("%s %q is not a non-negative integer", tag.name, v)
+			}
+			*tag.dst = &num
+		}
+	}
+
+	if v := field.Tag.Get("pattern"); v != "" {
+		if _, err := compilePattern(v); err != nil {
+			return fmt.Errorf("pattern %q: %w", v, err)
+		}
+		item.Pattern = v
+	}
+
+	if v := field.Tag.Get("format"); v != "" {
+		item.Format = v
+	}
+
+	if v := field.Tag.Get("default"); v != "" {
+		value, err := parseValue(v)
+		if err != nil {
+			return fmt.Errorf("default %q: %w", v, err)
+		}
+		// a $ref only resolves against the complete schema, so referenced defaults are not checked here
+		if item.Ref == "" {
+			if err = item.Validate(value); err != nil {
+				return fmt.Errorf("default %q: %w", v, err)
+			}
+		}
+		item.Default = value
+	}
+
+	if v := field.Tag.Get("examples"); v != "" {
+		// a JSON array, so that examples may contain any character
+		var examples []json.RawMessage
+		if err := pkg.JSONUnmarshal([]byte(v), &examples); err != nil {
+			return fmt.Errorf("examples %q: not a JSON array: %w", v, err)
+		}
+		for _, example := range examples {
+			literal := string(example)
+			// strings are given as the literal of a default tag, eg. "1s" for a time.Duration
+			var text string
+			if err := pkg.JSONUnmarshal(example, &text); err == nil {
+				literal = text
+			}
+			value, err := parseValue(literal)
+			if err != nil {
+				return fmt.Errorf("example %s: %w", example, err)
+			}
+			item.Examples = append(item.Examples, value)
+		}
+	}
+	return nil
+}
+
+// parseTagValue converts the literal tag value into a JSON value compatible with t.
+func parseTagValue(t reflect.Type, v string) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	case t == durationType:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+		return int64(d), nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return v, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(v, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(v, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(v, 64)
+	case reflect.Bool:
+		return strconv.ParseBool(v)
+	default:
+		var value interface{}
+		if err := pkg.JSONUnmarshal([]byte(v), &value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+}
+
+func (g *schemaGenerator) reflectSchemaByType(t reflect.Type) (*Property, error) {
 	s := &Property{}
 
+	switch t {
+	case timeType:
+		return &Property{Type: String, Format: "date-time"}, nil
+	case durationType:
+		// encoding/json writes time.Duration as integer nanoseconds, which the "duration" format
+		// of JSON Schema (ISO 8601 strings) does not describe, unless the field is tagged with it
+		return &Property{Type: Integer, Description: durationDescription}, nil
+	case rawMessageType:
+		return &Property{}, nil
+	}
+
 	switch t.Kind() {
 	case reflect.String:
 		s.Type = String
@@ -187,13 +690,16 @@ func reflectSchemaByType(t reflect.Type) (*Property, error) {
 		s.Type = Boolean
 	case reflect.Slice, reflect.Array:
 		s.Type = Array
-		items, err := reflectSchemaByType(t.Elem())
+		items, err := g.reflectSchemaByType(t.Elem())
 		if err != nil {
 			return nil, err
 		}
 		s.Items = items
 	case reflect.Struct:
-		object, err := reflectSchemaByObject(t)
+		if g.counts[t] > 1 {
+			return g.reference(t)
+		}
+		object, err := g.reflectSchemaByObject(t)
 		if err != nil {
 			return nil, err
 		}
@@ -208,13 +714,25 @@ func reflectSchemaByType(t reflect.Type) (*Property, error) {
 		}
 		s = object
 	case reflect.Ptr:
-		p, err := reflectSchemaByType(t.Elem())
+		p, err := g.reflectSchemaByType(t.Elem())
 		if err != nil {
 			return nil, err
 		}
 		s = p
+	case reflect.Interface:
+		impls, ok := schemaImplementations.Load(getTypeUUID(t))
+		if !ok {
+			return nil, fmt.Errorf("unsupported type: %s", t.Kind().String())
+		}
+		for _, impl := range impls {
+			p, err := g.reflectSchemaByType(impl)
+			if err != nil {
+				return nil, err
+			}
+			s.OneOf = append(s.OneOf, p)
+		}
 	case reflect.Invalid, reflect.Uintptr, reflect.Complex64, reflect.Complex128,
-		reflect.Chan, reflect.Func, reflect.Interface,
+		reflect.Chan, reflect.Func,
 		reflect.UnsafePointer:
 		return nil, fmt.Errorf("unsupported type: %s", t.Kind().String())
 	default:
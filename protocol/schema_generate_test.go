@@ -1,8 +1,13 @@
 package protocol
 
 import (
+	"encoding/json"
+	"errors"
+	"reflect"
 	"sort"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGenerateSchemaFromReqStruct(t *testing.T) {
@@ -468,3 +473,293 @@ func compareProperty(a, b *Property) bool {
 
 	return true
 }
+
+func TestGenerateSchemaWithConstraintTags(t *testing.T) {
+	type constraintData struct {
+		Age      int             `json:"age" minimum:"0" maximum:"150" default:"18"`
+		Code     string          `json:"code" pattern:"^[A-Z]{3}$" minLength:"3" maxLength:"3" examples:"[\"ABC\", \"XYZ\"]"`
+		Address  string          `json:"address,omitempty" examples:"[\"1 Main St, Springfield\"]"`
+		Retries  []int           `json:"retries,omitempty" examples:"[[1, 2], [3]]"`
+		Email    string          `json:"email,omitempty" format:"email"`
+		Tags     []string        `json:"tags,omitempty" maxItems:"2"`
+		Created  time.Time       `json:"created"`
+		Deadline *time.Time      `json:"deadline,omitempty"`
+		Timeout  time.Duration   `json:"timeout,omitempty" default:"1s"`
+		Interval time.Duration   `json:"interval,omitempty" description:"polling interval"`
+		Extra    json.RawMessage `json:"extra,omitempty"`
+	}
+
+	schema, err := generateSchemaFromReqStruct(constraintData{})
+	if err != nil {
+		t.Fatalf("generateSchemaFromReqStruct() error = %v", err)
+	}
+
+	age := schema.Properties["age"]
+	if age.Minimum == nil || *age.Minimum != 0 || age.Maximum == nil || *age.Maximum != 150 || age.Default != int64(18) {
+		t.Errorf("age = %+v", age)
+	}
+	code := schema.Properties["code"]
+	if code.Pattern != "^[A-Z]{3}$" || *code.MinLength != 3 || *code.MaxLength != 3 || !reflect.DeepEqual(code.Examples, []interface{}{"ABC", "XYZ"}) {
+		t.Errorf("code = %+v", code)
+	}
+	if address := schema.Properties["address"]; !reflect.DeepEqual(address.Examples, []interface{}{"1 Main St, Springfield"}) {
+		t.Errorf("address = %+v, want the example with a comma kept whole", address)
+	}
+	if retries := schema.Properties["retries"]; len(retries.Examples) != 2 {
+		t.Errorf("retries = %+v, want two array examples", retries)
+	}
+	if email := schema.Properties["email"]; email.Format != "email" {
+		t.Errorf("email = %+v", email)
+	}
+	if tags := schema.Properties["tags"]; tags.MaxItems == nil || *tags.MaxItems != 2 {
+		t.Errorf("tags = %+v", tags)
+	}
+	for _, name := range []string{"created", "deadline"} {
+		if p := schema.Properties[name]; p.Type != String || p.Format != "date-time" {
+			t.Errorf("%s = %+v, want date-time string", name, p)
+		}
+	}
+	if timeout := schema.Properties["timeout"]; timeout.Type != Integer || timeout.Format != "" ||
+		timeout.Description != "duration in nanoseconds" || timeout.Default != int64(time.Second) {
+		t.Errorf("timeout = %+v", timeout)
+	}
+	if interval := schema.Properties["interval"]; interval.Description != "polling interval (duration in nanoseconds)" {
+		t.Errorf("interval = %+v", interval)
+	}
+	if extra := schema.Properties["extra"]; extra.Type != "" || extra.Items != nil {
+		t.Errorf("extra = %+v, want any", extra)
+	}
+
+	b, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	for _, want := range []string{`"minimum":0`, `"maximum":150`, `"default":18`, `"examples":["ABC","XYZ"]`, `"format":"date-time"`} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("Marshal() = %s, missing %s", b, want)
+		}
+	}
+
+	if err = VerifyAndUnmarshal(json.RawMessage(`{"age":200,"code":"abc","created":"2025-01-01T00:00:00Z"}`), &constraintData{}); err == nil {
+		t.Errorf("VerifyAndUnmarshal() expected error")
+	}
+	v := &constraintData{}
+	if err = VerifyAndUnmarshal(json.RawMessage(`{"code":"ABC","created":"2025-01-01T00:00:00Z","age":20}`), v); err != nil {
+		t.Fatalf("VerifyAndUnmarshal() error = %v", err)
+	}
+	if v.Timeout != time.Second {
+		t.Errorf("Timeout = %v, want default 1s", v.Timeout)
+	}
+}
+
+func TestGenerateSchemaWithISODuration(t *testing.T) {
+	type step struct {
+		Wait time.Duration `json:"wait" format:"duration"`
+	}
+	type isoDurationData struct {
+		Timeout time.Duration  `json:"timeout,omitempty" format:"duration" default:"PT1M30S"`
+		Delay   *time.Duration `json:"delay,omitempty" format:"duration"`
+		Steps   []step         `json:"steps,omitempty"`
+		Nanos   time.Duration  `json:"nanos,omitempty"`
+	}
+
+	schema, err := generateSchemaFromReqStruct(isoDurationData{})
+	if err != nil {
+		t.Fatalf("generateSchemaFromReqStruct() error = %v", err)
+	}
+	for _, name := range []string{"timeout", "delay"} {
+		if p := schema.Properties[name]; p.Type != String || p.Format != "duration" || p.Description != "" {
+			t.Errorf("%s = %+v, want duration string", name, p)
+		}
+	}
+	if timeout := schema.Properties["timeout"]; timeout.Default != "PT1M30S" {
+		t.Errorf("timeout default = %v, want PT1M30S", timeout.Default)
+	}
+	if nanos := schema.Properties["nanos"]; nanos.Type != Integer {
+		t.Errorf("nanos = %+v, want nanoseconds without the tag", nanos)
+	}
+
+	v := &isoDurationData{}
+	if err = VerifyAndUnmarshal(json.RawMessage(`{"delay":"P1DT2H","steps":[{"wait":"PT0.5S"}],"nanos":5}`), v); err != nil {
+		t.Fatalf("VerifyAndUnmarshal() error = %v", err)
+	}
+	if v.Timeout != 90*time.Second || v.Delay == nil || *v.Delay != 26*time.Hour ||
+		len(v.Steps) != 1 || v.Steps[0].Wait != 500*time.Millisecond || v.Nanos != 5 {
+		t.Errorf("VerifyAndUnmarshal() = %+v", v)
+	}
+
+	for _, arguments := range []string{`{"delay":"1h"}`, `{"delay":"P1M"}`, `{"delay":"PT"}`, `{"steps":[{"wait":3}]}`} {
+		err = VerifyAndUnmarshal(json.RawMessage(arguments), &isoDurationData{})
+		var errs ValidationErrors
+		if !errors.As(err, &errs) {
+			t.Errorf("VerifyAndUnmarshal(%s) error = %v, want a validation error", arguments, err)
+		}
+	}
+
+	if _, err = generateSchemaFromReqStruct(struct {
+		Timeout time.Duration `json:"timeout" format:"duration" default:"1s"`
+	}{}); err == nil {
+		t.Errorf("generateSchemaFromReqStruct() with a Go duration default expected error")
+	}
+}
+
+func TestParseISODuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "PT1M30S", want: 90 * time.Second},
+		{value: "P1W", want: 7 * 24 * time.Hour},
+		{value: "P1DT2H", want: 26 * time.Hour},
+		{value: "PT1,5H", want: 90 * time.Minute},
+		{value: "P0Y0M1D", want: 24 * time.Hour},
+		{value: "P1Y", wantErr: true},
+		{value: "P", wantErr: true},
+		{value: "P1DT", wantErr: true},
+		{value: "PT1S2M", wantErr: true},
+		{value: "P999999999999W", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseISODuration(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseISODuration(%q) = %v, %v, want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestGenerateSchemaWithInvalidConstraintTags(t *testing.T) {
+	for _, v := range []any{
+		struct {
+			A int `json:"a" minimum:"x"`
+		}{},
+		struct {
+			A string `json:"a" maxLength:"-1"`
+		}{},
+		struct {
+			A string `json:"a" pattern:"("`
+		}{},
+		struct {
+			A int `json:"a" maximum:"5" default:"6"`
+		}{},
+		struct {
+			A string `json:"a" examples:"ABC,XYZ"`
+		}{},
+		struct {
+			A int `json:"a" examples:"[1, \"two\"]"`
+		}{},
+	} {
+		if _, err := generateSchemaFromReqStruct(v); err == nil {
+			t.Errorf("generateSchemaFromReqStruct(%T) expected error", v)
+		}
+	}
+}
+
+type treeNode struct {
+	Name     string      `json:"name"`
+	Children []*treeNode `json:"children,omitempty"`
+}
+
+type address struct {
+	City string `json:"city"`
+}
+
+type shipment struct {
+	From address   `json:"from"`
+	To   address   `json:"to"`
+	Root *treeNode `json:"root,omitempty"`
+}
+
+type filter interface {
+	isFilter()
+}
+
+type equalFilter struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+func (equalFilter) isFilter() {}
+
+type andFilter struct {
+	And []filter `json:"and"`
+}
+
+func (andFilter) isFilter() {}
+
+type searchRequest struct {
+	Filter filter `json:"filter"`
+}
+
+func TestGenerateSchemaWithRefs(t *testing.T) {
+	schema, err := generateSchemaFromReqStruct(shipment{})
+	if err != nil {
+		t.Fatalf("generateSchemaFromReqStruct() error = %v", err)
+	}
+
+	for _, name := range []string{"from", "to"} {
+		if ref := schema.Properties[name].Ref; ref != "#/$defs/address" {
+			t.Errorf("%s.$ref = %q, want #/$defs/address", name, ref)
+		}
+	}
+	if ref := schema.Properties["root"].Ref; ref != "#/$defs/treeNode" {
+		t.Errorf("root.$ref = %q, want #/$defs/treeNode", ref)
+	}
+	node := schema.Defs["treeNode"]
+	if node == nil || node.Properties["children"].Items.Ref != "#/$defs/treeNode" {
+		t.Fatalf("$defs.treeNode = %+v", node)
+	}
+
+	if err = VerifyAndUnmarshal(json.RawMessage(`{"from":{"city":"a"},"to":{"city":"b"},"root":{"name":"r","children":[{"name":"c"}]}}`), &shipment{}); err != nil {
+		t.Errorf("VerifyAndUnmarshal() error = %v", err)
+	}
+	err = VerifyAndUnmarshal(json.RawMessage(`{"from":{"city":"a"},"to":{},"root":{"name":"r","children":[{}]}}`), &shipment{})
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("VerifyAndUnmarshal() error = %v, want 2 violations", err)
+	}
+	if paths := []string{errs[0].Path, errs[1].Path}; !reflect.DeepEqual(paths, []string{"/root/children/0/name", "/to/city"}) &&
+		!reflect.DeepEqual(paths, []string{"/to/city", "/root/children/0/name"}) {
+		t.Errorf("VerifyAndUnmarshal() error = %v, want violations at /to/city and /root/children/0/name", err)
+	}
+
+	recursive, err := generateSchemaFromReqStruct(treeNode{})
+	if err != nil {
+		t.Fatalf("generateSchemaFromReqStruct() error = %v", err)
+	}
+	if ref := recursive.Properties["children"].Items.Ref; ref != "#" || len(recursive.Defs) != 0 {
+		t.Errorf("children.items.$ref = %q, $defs = %v, want # and no $defs", ref, recursive.Defs)
+	}
+}
+
+func TestGenerateSchemaWithImplementations(t *testing.T) {
+	if _, err := generateSchemaFromReqStruct(struct {
+		Filter filter `json:"filter"`
+	}{}); err == nil {
+		t.Fatalf("generateSchemaFromReqStruct() expected error for unregistered interface")
+	}
+
+	if err := RegisterSchemaImplementations[filter](equalFilter{}, &andFilter{}); err != nil {
+		t.Fatalf("RegisterSchemaImplementations() error = %v", err)
+	}
+	if err := RegisterSchemaImplementations[equalFilter](); err == nil {
+		t.Errorf("RegisterSchemaImplementations() expected error for non-interface type")
+	}
+
+	schema, err := generateSchemaFromReqStruct(searchRequest{})
+	if err != nil {
+		t.Fatalf("generateSchemaFromReqStruct() error = %v", err)
+	}
+	oneOf := schema.Properties["filter"].OneOf
+	if len(oneOf) != 2 || oneOf[0].Ref != "#/$defs/equalFilter" || oneOf[1].Ref != "#/$defs/andFilter" {
+		t.Fatalf("filter.oneOf = %+v", oneOf)
+	}
+
+	property := schema.toProperty()
+	if err = property.ValidateJSON(json.RawMessage(`{"filter":{"and":[{"field":"a","value":"1"},{"and":[]}]}}`)); err != nil {
+		t.Errorf("ValidateJSON() error = %v", err)
+	}
+	if err = property.ValidateJSON(json.RawMessage(`{"filter":{"and":[{"field":"a"}]}}`)); err == nil {
+		t.Errorf("ValidateJSON() expected error")
+	}
+}
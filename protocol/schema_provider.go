@@ -0,0 +1,117 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+)
+
+// SchemaProvider supplies the input schema of a tool as a JSON Schema document,
+// so that schemas can be maintained outside Go, eg. as .json files shared with other languages.
+type SchemaProvider interface {
+	InputSchema() (json.RawMessage, error)
+}
+
+// SchemaProviderFunc adapts a function, eg. an external schema generator, to SchemaProvider.
+type SchemaProviderFunc func() (json.RawMessage, error)
+
+func (f SchemaProviderFunc) InputSchema() (json.RawMessage, error) {
+	return f()
+}
+
+// RawSchema provides a schema document held in memory.
+type RawSchema json.RawMessage
+
+func (s RawSchema) InputSchema() (json.RawMessage, error) {
+	return json.RawMessage(s), nil
+}
+
+// FSSchema provides the schema document stored at path in fsys, eg. an embed.FS:
+//
+//	//go:embed schemas
+//	var schemas embed.FS
+//	tool, err := protocol.NewToolWithSchema("search", "search documents", protocol.FSSchema(schemas, "schemas/search.json"))
+func FSSchema(fsys fs.FS, path string) SchemaProvider {
+	return SchemaProviderFunc(func() (json.RawMessage, error) {
+		content, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return nil, fmt.Errorf("read schema %s: %w", path, err)
+		}
+		return content, nil
+	})
+}
+
+// ParseInputSchema parses a tool input schema document and checks that it is usable,
+// the top level must describe an object, every pattern must compile and every $ref must resolve.
+func ParseInputSchema(raw json.RawMessage) (*Property, error) {
+	var schema *Property
+	if err := pkg.JSONUnmarshal(raw, &schema); err != nil {
+		return nil, fmt.Errorf("invalid input schema: %w", err)
+	}
+	if schema == nil {
+		return nil, fmt.Errorf("invalid input schema: null")
+	}
+	if schema.Type != ObjectT || schema.Nullable {
+		return nil, fmt.Errorf("invalid input schema: type must be %q", ObjectT)
+	}
+
+	v := &validator{root: schema}
+	if err := v.checkSchema(schema, ""); err != nil {
+		return nil, fmt.Errorf("invalid input schema: %w", err)
+	}
+	return schema, nil
+}
+
+// checkSchema walks the schema without following $ref, so recursive documents terminate.
+func (v *validator) checkSchema(schema *Property, path string) error {
+	if schema == nil {
+		return nil
+	}
+
+	if schema.Ref != "" {
+		if _, err := v.resolveRef(schema.Ref); err != nil {
+			return fmt.Errorf("%s: %w", pointerOrRoot(path), err)
+		}
+	}
+	if schema.Pattern != "" {
+		if _, err := compilePattern(schema.Pattern); err != nil {
+			return fmt.Errorf("%s: pattern %q: %w", pointerOrRoot(path), schema.Pattern, err)
+		}
+	}
+
+	for name, prop := range schema.Properties {
+		if err := v.checkSchema(prop, joinPointer(joinPointer(path, "properties"), name)); err != nil {
+			return err
+		}
+	}
+	if err := v.checkSchema(schema.Items, joinPointer(path, "items")); err != nil {
+		return err
+	}
+	if additional, ok := schema.AdditionalProperties.(*Property); ok {
+		if err := v.checkSchema(additional, joinPointer(path, "additionalProperties")); err != nil {
+			return err
+		}
+	}
+	for name, def := range schema.Defs {
+		if err := v.checkSchema(def, joinPointer(joinPointer(path, "$defs"), name)); err != nil {
+			return err
+		}
+	}
+	for keyword, branches := range map[string][]*Property{"oneOf": schema.OneOf, "anyOf": schema.AnyOf, "allOf": schema.AllOf} {
+		for i, branch := range branches {
+			if err := v.checkSchema(branch, joinPointer(joinPointer(path, keyword), fmt.Sprint(i))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func pointerOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
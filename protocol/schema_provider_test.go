@@ -0,0 +1,70 @@
+package protocol
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNewToolWithSchema(t *testing.T) {
+	fsys := fstest.MapFS{
+		"schemas/search.json": {Data: []byte(`{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"type": "object",
+			"properties": {
+				"query": {"type": "string", "minLength": 1},
+				"filter": {"$ref": "#/$defs/filter"}
+			},
+			"required": ["query"],
+			"additionalProperties": false,
+			"$defs": {
+				"filter": {"type": "object", "properties": {"lang": {"type": "string", "pattern": "^[a-z]{2}$"}}}
+			}
+		}`)},
+	}
+
+	tool, err := NewToolWithSchema("search", "search documents", FSSchema(fsys, "schemas/search.json"))
+	if err != nil {
+		t.Fatalf("NewToolWithSchema() error = %v", err)
+	}
+	if tool.InputSchema.Type != Object || tool.InputSchema.Properties["query"] == nil || tool.InputSchema.Defs["filter"] == nil {
+		t.Errorf("InputSchema = %+v", tool.InputSchema)
+	}
+
+	b, err := json.Marshal(tool)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(b), `"$schema":"https://json-schema.org/draft/2020-12/schema"`) {
+		t.Errorf("Marshal() = %s, want the raw document", b)
+	}
+
+	if err = tool.VerifyArguments(json.RawMessage(`{"query":"go","filter":{"lang":"en"}}`)); err != nil {
+		t.Errorf("VerifyArguments() error = %v", err)
+	}
+	if err = tool.VerifyArguments(json.RawMessage(`{"query":"go","filter":{"lang":"eng"},"page":1}`)); err == nil {
+		t.Errorf("VerifyArguments() expected error")
+	}
+
+	if _, err = NewToolWithSchema("search", "", FSSchema(fsys, "schemas/missing.json")); err == nil {
+		t.Errorf("NewToolWithSchema() expected error for missing file")
+	}
+}
+
+func TestParseInputSchemaInvalid(t *testing.T) {
+	for name, raw := range map[string]string{
+		"syntax":  `{"type": "object",`,
+		"type":    `{"type": "string"}`,
+		"pattern": `{"type": "object", "properties": {"a": {"type": "string", "pattern": "("}}}`,
+		"ref":     `{"type": "object", "properties": {"a": {"$ref": "#/$defs/missing"}}}`,
+	} {
+		if _, err := ParseInputSchema(json.RawMessage(raw)); err == nil {
+			t.Errorf("ParseInputSchema(%s) expected error", name)
+		}
+	}
+
+	if _, err := NewToolWithRawSchema("broken", "", json.RawMessage(`{"type": "string"}`)); err == nil {
+		t.Errorf("NewToolWithRawSchema() expected error")
+	}
+}
@@ -2,14 +2,52 @@ package protocol
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/ThinkInAIXYZ/go-mcp/pkg"
 )
 
+// maxValidateDepth guards against schemas whose $ref chain never consumes any data.
+const maxValidateDepth = 256
+
+// ValidationError describes a single violation of a schema.
+type ValidationError struct {
+	// Path is the JSON pointer (RFC 6901) of the offending value, "" is the document root.
+	Path string `json:"path"`
+	// Keyword is the schema keyword that failed, e.g. "required" or "minimum".
+	Keyword string `json:"keyword"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Sprintf("%s: %s", path, e.Message)
+}
+
+// ValidationErrors collects every violation found while validating a value.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return "data validation failed against the provided schema: " + strings.Join(messages, "; ")
+}
+
 func VerifyAndUnmarshal(content json.RawMessage, v any) error {
 	if len(content) == 0 {
 		return fmt.Errorf("request arguments is empty")
@@ -29,11 +67,27 @@ func VerifyAndUnmarshal(content json.RawMessage, v any) error {
 		return fmt.Errorf("schema has not been generated，unable to verify: plz use func `pkg.JSONUnmarshal` instead")
 	}
 
-	return verifySchemaAndUnmarshal(Property{
-		Type:       ObjectT,
-		Properties: schema.Properties,
-		Required:   schema.Required,
-	}, content, v)
+	return verifySchemaAndUnmarshal(schema.toProperty(), content, v)
+}
+
+// Validate checks data, as produced by json.Unmarshal into an interface{}, against the schema.
+// It returns ValidationErrors listing every violation, or nil.
+func (p *Property) Validate(data any) error {
+	v := &validator{root: p}
+	v.validate(p, data, "", 0)
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// ValidateJSON is like Validate but takes the encoded JSON document.
+func (p *Property) ValidateJSON(content json.RawMessage) error {
+	var data any
+	if err := pkg.JSONUnmarshal(content, &data); err != nil {
+		return err
+	}
+	return p.Validate(data)
 }
 
 func verifySchemaAndUnmarshal(schema Property, content []byte, v any) error {
@@ -42,79 +96,323 @@ func verifySchemaAndUnmarshal(schema Property, content []byte, v any) error {
 	if err != nil {
 		return err
 	}
-	if !validate(schema, data) {
-		return errors.New("data validation failed against the provided schema")
+	if err = schema.Validate(data); err != nil {
+		return err
+	}
+
+	va := &validator{root: &schema}
+	changed := va.applyDefaults(&schema, data, 0)
+	var errs ValidationErrors
+	if decodeISODurations(reflect.TypeOf(v), data, "", &errs) {
+		changed = true
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	if changed {
+		if content, err = json.Marshal(data); err != nil {
+			return err
+		}
 	}
 	return pkg.JSONUnmarshal(content, &v)
 }
 
-func validate(schema Property, data any) bool {
-	switch schema.Type {
-	case ObjectT:
-		return validateObject(schema, data)
-	case Array:
-		return validateArray(schema, data)
-	case String:
-		str, ok := data.(string)
-		if ok {
-			return validateEnumProperty[string](str, schema.Enum, func(value string, enumValue string) bool {
-				return value == enumValue
-			})
+type validator struct {
+	root *Property
+	errs ValidationErrors
+}
+
+func (v *validator) addError(path, keyword, format string, a ...any) {
+	v.errs = append(v.errs, &ValidationError{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, a...)})
+}
+
+// try validates data against schema in isolation and returns the violations found.
+func (v *validator) try(schema *Property, data any, path string, depth int) ValidationErrors {
+	sub := &validator{root: v.root}
+	sub.validate(schema, data, path, depth)
+	return sub.errs
+}
+
+func (v *validator) validate(schema *Property, data any, path string, depth int) {
+	if schema == nil {
+		return
+	}
+	if depth > maxValidateDepth {
+		v.addError(path, "$ref", "schema nesting exceeds %d levels", maxValidateDepth)
+		return
+	}
+
+	if data == nil && schema.Nullable {
+		return
+	}
+
+	if schema.Ref != "" {
+		target, err := v.resolveRef(schema.Ref)
+		if err != nil {
+			v.addError(path, "$ref", "%v", err)
+			return
 		}
-		return false
-	case Number: // float64 and int
-		if num, ok := data.(float64); ok {
-			return validateEnumProperty[float64](num, schema.Enum, func(value float64, enumValue string) bool {
-				if enumNum, err := strconv.ParseFloat(enumValue, 64); err == nil && value == enumNum {
-					return true
-				}
-				return false
-			})
-		}
-		if num, ok := data.(int); ok {
-			return validateEnumProperty[int](num, schema.Enum, func(value int, enumValue string) bool {
-				if enumNum, err := strconv.Atoi(enumValue); err == nil && value == enumNum {
-					return true
-				}
-				return false
-			})
+		v.validate(target, data, path, depth+1)
+	}
+
+	if schema.Type != "" && !matchesType(schema.Type, data) {
+		v.addError(path, "type", "expected %s, got %s", schema.Type, jsonTypeOf(data))
+		return
+	}
+
+	if schema.Const != nil && !jsonEqual(schema.Const, data) {
+		v.addError(path, "const", "must be equal to %s", marshalForMessage(schema.Const))
+	}
+	if len(schema.Enum) > 0 && !matchesEnum(schema.Enum, data) {
+		v.addError(path, "enum", "must be one of [%s]", strings.Join(schema.Enum, ", "))
+	}
+
+	switch value := data.(type) {
+	case string:
+		v.validateString(schema, value, path)
+	case map[string]any:
+		v.validateObject(schema, value, path, depth)
+	case []any:
+		v.validateArray(schema, value, path, depth)
+	default:
+		if num, ok := toFloat64(data); ok {
+			v.validateNumber(schema, num, path)
 		}
-		return false
-	case Boolean:
-		_, ok := data.(bool)
-		return ok
-	case Integer:
-		// Golang unmarshals all numbers as float64, so we need to check if the float64 is an integer
-		if num, ok := data.(float64); ok {
-			if num == float64(int64(num)) {
-				return validateEnumProperty[float64](num, schema.Enum, func(value float64, enumValue string) bool {
-					if enumNum, err := strconv.ParseFloat(enumValue, 64); err == nil && value == enumNum {
-						return true
-					}
-					return false
-				})
+	}
+
+	v.validateComposition(schema, data, path, depth)
+}
+
+func (v *validator) validateString(schema *Property, value, path string) {
+	length := utf8.RuneCountInString(value)
+	if schema.MinLength != nil && length < *schema.MinLength {
+		v.addError(path, "minLength", "length must be >= %d, got %d", *schema.MinLength, length)
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		v.addError(path, "maxLength", "length must be <= %d, got %d", *schema.MaxLength, length)
+	}
+	if schema.Pattern != "" {
+		re, err := compilePattern(schema.Pattern)
+		if err != nil {
+			v.addError(path, "pattern", "invalid pattern %q: %v", schema.Pattern, err)
+		} else if !re.MatchString(value) {
+			v.addError(path, "pattern", "must match pattern %q", schema.Pattern)
+		}
+	}
+	if schema.Format != "" {
+		if err := checkFormat(schema.Format, value); err != nil {
+			v.addError(path, "format", "must be a valid %s: %v", schema.Format, err)
+		}
+	}
+}
+
+func (v *validator) validateNumber(schema *Property, num float64, path string) {
+	if schema.Minimum != nil && num < *schema.Minimum {
+		v.addError(path, "minimum", "must be >= %v, got %v", *schema.Minimum, num)
+	}
+	if schema.Maximum != nil && num > *schema.Maximum {
+		v.addError(path, "maximum", "must be <= %v, got %v", *schema.Maximum, num)
+	}
+	if schema.ExclusiveMinimum != nil && num <= *schema.ExclusiveMinimum {
+		v.addError(path, "exclusiveMinimum", "must be > %v, got %v", *schema.ExclusiveMinimum, num)
+	}
+	if schema.ExclusiveMaximum != nil && num >= *schema.ExclusiveMaximum {
+		v.addError(path, "exclusiveMaximum", "must be < %v, got %v", *schema.ExclusiveMaximum, num)
+	}
+}
+
+func (v *validator) validateObject(schema *Property, value map[string]any, path string, depth int) {
+	for _, field := range schema.Required {
+		if _, exists := value[field]; !exists {
+			v.addError(joinPointer(path, field), "required", "missing required property %q", field)
+		}
+	}
+
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if propSchema, ok := schema.Properties[key]; ok {
+			v.validate(propSchema, value[key], joinPointer(path, key), depth+1)
+			continue
+		}
+
+		switch additional := schema.AdditionalProperties.(type) {
+		case bool:
+			if !additional {
+				v.addError(joinPointer(path, key), "additionalProperties", "property %q is not allowed", key)
 			}
-			return false
+		case *Property:
+			v.validate(additional, value[key], joinPointer(path, key), depth+1)
 		}
+	}
+}
+
+func (v *validator) validateArray(schema *Property, value []any, path string, depth int) {
+	if schema.MinItems != nil && len(value) < *schema.MinItems {
+		v.addError(path, "minItems", "must contain at least %d items, got %d", *schema.MinItems, len(value))
+	}
+	if schema.MaxItems != nil && len(value) > *schema.MaxItems {
+		v.addError(path, "maxItems", "must contain at most %d items, got %d", *schema.MaxItems, len(value))
+	}
+	if schema.Items == nil {
+		return
+	}
+	for i, item := range value {
+		v.validate(schema.Items, item, joinPointer(path, strconv.Itoa(i)), depth+1)
+	}
+}
+
+func (v *validator) validateComposition(schema *Property, data any, path string, depth int) {
+	for _, sub := range schema.AllOf {
+		v.validate(sub, data, path, depth+1)
+	}
 
-		if num, ok := data.(int); ok {
-			return validateEnumProperty[int](num, schema.Enum, func(value int, enumValue string) bool {
-				if enumNum, err := strconv.Atoi(enumValue); err == nil && value == enumNum {
-					return true
-				}
-				return false
-			})
+	if len(schema.AnyOf) > 0 {
+		var closest ValidationErrors
+		matched := false
+		for _, sub := range schema.AnyOf {
+			errs := v.try(sub, data, path, depth+1)
+			if len(errs) == 0 {
+				matched = true
+				break
+			}
+			if closest == nil || len(errs) < len(closest) {
+				closest = errs
+			}
 		}
+		if !matched {
+			v.addError(path, "anyOf", "must match at least one schema in anyOf")
+			v.errs = append(v.errs, closest...)
+		}
+	}
 
-		if num, ok := data.(int64); ok {
-			return validateEnumProperty[int64](num, schema.Enum, func(value int64, enumValue string) bool {
-				if enumNum, err := strconv.Atoi(enumValue); err == nil && value == int64(enumNum) {
-					return true
-				}
-				return false
-			})
+	if len(schema.OneOf) > 0 {
+		var closest ValidationErrors
+		matched := 0
+		for _, sub := range schema.OneOf {
+			errs := v.try(sub, data, path, depth+1)
+			if len(errs) == 0 {
+				matched++
+				continue
+			}
+			if closest == nil || len(errs) < len(closest) {
+				closest = errs
+			}
+		}
+		switch {
+		case matched == 0:
+			v.addError(path, "oneOf", "must match exactly one schema in oneOf, matched none")
+			v.errs = append(v.errs, closest...)
+		case matched > 1:
+			v.addError(path, "oneOf", "must match exactly one schema in oneOf, matched %d", matched)
 		}
+	}
+}
+
+// applyDefaults fills absent object properties that declare a default, and reports whether data changed.
+func (v *validator) applyDefaults(schema *Property, data any, depth int) bool {
+	if schema == nil || depth > maxValidateDepth {
 		return false
+	}
+	if schema.Ref != "" {
+		target, err := v.resolveRef(schema.Ref)
+		if err != nil {
+			return false
+		}
+		return v.applyDefaults(target, data, depth+1)
+	}
+
+	changed := false
+	switch value := data.(type) {
+	case map[string]any:
+		for key, propSchema := range schema.Properties {
+			if _, exists := value[key]; !exists && propSchema.Default != nil {
+				value[key] = propSchema.Default
+				changed = true
+				continue
+			}
+			if v.applyDefaults(propSchema, value[key], depth+1) {
+				changed = true
+			}
+		}
+	case []any:
+		for _, item := range value {
+			if v.applyDefaults(schema.Items, item, depth+1) {
+				changed = true
+			}
+		}
+	}
+	for _, sub := range schema.AllOf {
+		if v.applyDefaults(sub, data, depth+1) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// resolveRef resolves local references such as "#", "#/$defs/Name" or "#/properties/a/items" against the root schema.
+func (v *validator) resolveRef(ref string) (*Property, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported non-local $ref %q", ref)
+	}
+
+	current := v.root
+	tokens := strings.Split(strings.TrimPrefix(ref, "#"), "/")
+	for i := 1; i < len(tokens); i++ {
+		token := unescapePointer(tokens[i])
+		var next *Property
+		switch token {
+		case "$defs", "definitions", "properties":
+			if i+1 >= len(tokens) {
+				return nil, fmt.Errorf("invalid $ref %q", ref)
+			}
+			i++
+			name := unescapePointer(tokens[i])
+			if token == "properties" {
+				next = current.Properties[name]
+			} else {
+				next = current.Defs[name]
+			}
+		case "items":
+			next = current.Items
+		case "additionalProperties":
+			next, _ = current.AdditionalProperties.(*Property)
+		default:
+			return nil, fmt.Errorf("unsupported $ref %q", ref)
+		}
+		if next == nil {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		current = next
+	}
+	return current, nil
+}
+
+func matchesType(t DataType, data any) bool {
+	switch t {
+	case ObjectT:
+		_, ok := data.(map[string]any)
+		return ok
+	case Array:
+		_, ok := data.([]any)
+		return ok
+	case String:
+		_, ok := data.(string)
+		return ok
+	case Number:
+		_, ok := toFloat64(data)
+		return ok
+	case Integer:
+		// Golang unmarshals all numbers as float64, so we need to check if the float64 is an integer
+		num, ok := toFloat64(data)
+		return ok && num == math.Trunc(num) && !math.IsInf(num, 0)
+	case Boolean:
+		_, ok := data.(bool)
+		return ok
 	case Null:
 		return data == nil
 	default:
@@ -122,43 +420,213 @@ func validate(schema Property, data any) bool {
 	}
 }
 
-func validateObject(schema Property, data any) bool {
-	dataMap, ok := data.(map[string]any)
-	if !ok {
-		return false
+func jsonTypeOf(data any) string {
+	switch data.(type) {
+	case nil:
+		return string(Null)
+	case map[string]any:
+		return string(ObjectT)
+	case []any:
+		return string(Array)
+	case string:
+		return string(String)
+	case bool:
+		return string(Boolean)
 	}
-	for _, field := range schema.Required {
-		if _, exists := dataMap[field]; !exists {
-			return false
+	if num, ok := toFloat64(data); ok {
+		if num == math.Trunc(num) {
+			return string(Integer)
 		}
+		return string(Number)
 	}
-	for key, valueSchema := range schema.Properties {
-		value, exists := dataMap[key]
-		if exists && !validate(*valueSchema, value) {
-			return false
-		}
+	return fmt.Sprintf("%T", data)
+}
+
+func toFloat64(data any) (float64, bool) {
+	switch n := data.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	default:
+		return 0, false
 	}
-	return true
 }
 
-func validateArray(schema Property, data any) bool {
-	dataArray, ok := data.([]any)
-	if !ok {
+// matchesEnum compares data with the enum values, which are kept as strings.
+// Enum only constrains scalar values, arrays and objects are left to the other keywords.
+func matchesEnum(enum []string, data any) bool {
+	switch value := data.(type) {
+	case string:
+		for _, e := range enum {
+			if value == e {
+				return true
+			}
+		}
+		return false
+	case bool:
+		for _, e := range enum {
+			if strconv.FormatBool(value) == e {
+				return true
+			}
+		}
+		return false
+	case nil:
+		for _, e := range enum {
+			if e == string(Null) {
+				return true
+			}
+		}
 		return false
 	}
-	for _, item := range dataArray {
-		if !validate(*schema.Items, item) {
-			return false
+
+	num, ok := toFloat64(data)
+	if !ok {
+		return true
+	}
+	for _, e := range enum {
+		if enumNum, err := strconv.ParseFloat(e, 64); err == nil && num == enumNum {
+			return true
 		}
 	}
-	return true
+	return false
 }
 
-func validateEnumProperty[T any](data T, enum []string, compareFunc func(T, string) bool) bool {
-	for _, enumValue := range enum {
-		if compareFunc(data, enumValue) {
-			return true
+// jsonEqual compares two values by their JSON representation so that e.g. int(1) equals float64(1).
+func jsonEqual(a, b any) bool {
+	return reflect.DeepEqual(normalizeJSONValue(a), normalizeJSONValue(b))
+}
+
+func normalizeJSONValue(value any) any {
+	if num, ok := toFloat64(value); ok {
+		return num
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for key, item := range v {
+			m[key] = normalizeJSONValue(item)
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, item := range v {
+			s[i] = normalizeJSONValue(item)
+		}
+		return s
+	case nil, string, bool:
+		return v
+	}
+
+	// Go values such as structs or typed slices, round trip them through JSON
+	b, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var decoded any
+	if err = json.Unmarshal(b, &decoded); err != nil {
+		return value
+	}
+	return normalizeJSONValue(decoded)
+}
+
+func marshalForMessage(value any) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+var patternCache = pkg.SyncMap[*regexp.Regexp]{}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patternCache.Load(pattern); ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternCache.Store(pattern, re)
+	return re, nil
+}
+
+var uuidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func checkFormat(format, value string) error {
+	switch format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339Nano, value); err != nil {
+			return fmt.Errorf("expected RFC 3339 date-time")
 		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return fmt.Errorf("expected RFC 3339 full-date")
+		}
+	case "time":
+		if _, err := time.Parse("15:04:05Z07:00", value); err != nil {
+			if _, err = time.Parse("15:04:05.999999999Z07:00", value); err != nil {
+				return fmt.Errorf("expected RFC 3339 full-time")
+			}
+		}
+	case "uri":
+		u, err := url.Parse(value)
+		if err != nil {
+			return err
+		}
+		if !u.IsAbs() {
+			return fmt.Errorf("expected an absolute URI with scheme")
+		}
+	case "email":
+		addr, err := mail.ParseAddress(value)
+		if err != nil {
+			return err
+		}
+		if addr.Address != value {
+			return fmt.Errorf("expected a bare address")
+		}
+	case "uuid":
+		if !uuidRegexp.MatchString(value) {
+			return fmt.Errorf("expected 8-4-4-4-12 hex digits")
+		}
+	case isoDurationFormat:
+		return checkISODuration(value)
 	}
-	return len(enum) == 0
+	return nil
+}
+
+// joinPointer appends a reference token to a JSON pointer, escaping "~" and "/" as per RFC 6901.
+func joinPointer(path, token string) string {
+	token = strings.ReplaceAll(token, "~", "~0")
+	token = strings.ReplaceAll(token, "/", "~1")
+	return path + "/" + token
+}
+
+func unescapePointer(token string) string {
+	token = strings.ReplaceAll(token, "~1", "/")
+	return strings.ReplaceAll(token, "~0", "~")
 }
@@ -2,7 +2,9 @@ package protocol
 
 import (
 	"encoding/json"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -219,8 +221,13 @@ func Test_Validate(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := validate(tt.args.schema, tt.args.data); got != tt.want {
-				t.Errorf("validate() = %v, want %v", got, tt.want)
+			err := tt.args.schema.Validate(tt.args.data)
+			if got := err == nil; got != tt.want {
+				t.Errorf("Validate() = %v, want valid %v", err, tt.want)
+			}
+			var errs ValidationErrors
+			if err != nil && (!errors.As(err, &errs) || len(errs) == 0) {
+				t.Errorf("Validate() = %#v, want ValidationErrors", err)
 			}
 		})
 	}
@@ -462,3 +469,132 @@ func TestVerifyAndUnmarshal(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateKeywords(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	floatPtr := func(f float64) *float64 { return &f }
+
+	schema := &Property{
+		Type: ObjectT,
+		Properties: map[string]*Property{
+			"age":     {Type: Integer, Minimum: floatPtr(0), Maximum: floatPtr(150)},
+			"name":    {Type: String, MinLength: intPtr(2), MaxLength: intPtr(8), Pattern: "^[a-z]+$"},
+			"email":   {Type: String, Format: "email"},
+			"id":      {Type: String, Format: "uuid"},
+			"site":    {Type: String, Format: "uri"},
+			"created": {Type: String, Format: "date-time"},
+			"kind":    {Const: "user"},
+			"nick":    {Type: String, Nullable: true},
+			"tags":    {Type: Array, Items: &Property{Type: String}, MaxItems: intPtr(2)},
+			"owner":   {Ref: "#/$defs/owner"},
+			"value":   {OneOf: []*Property{{Type: String}, {Type: Integer}}},
+			"limit":   {AnyOf: []*Property{{Type: Integer, Minimum: floatPtr(1)}, {Const: "all"}}},
+			"range":   {AllOf: []*Property{{Type: Number, Minimum: floatPtr(0)}, {Type: Number, Maximum: floatPtr(10)}}},
+		},
+		AdditionalProperties: false,
+		Defs: map[string]*Property{
+			"owner": {
+				Type:       ObjectT,
+				Properties: map[string]*Property{"name": {Type: String}},
+				Required:   []string{"name"},
+			},
+		},
+	}
+
+	valid := `{"age":30,"name":"abc","email":"a@b.co","id":"123e4567-e89b-12d3-a456-426614174000",
+		"site":"https://example.com","created":"2025-01-02T03:04:05Z","kind":"user","nick":null,
+		"tags":["a"],"owner":{"name":"x"},"value":1,"limit":"all","range":5}`
+	if err := schema.ValidateJSON(json.RawMessage(valid)); err != nil {
+		t.Fatalf("ValidateJSON() unexpected error = %v", err)
+	}
+
+	invalid := `{"age":-1,"name":"A","email":"nope","id":"x","site":"relative/path","created":"yesterday",
+		"kind":"admin","nick":1,"tags":["a","b",3],"owner":{},"value":1.5,"limit":0,"range":11,"extra":true}`
+	err := schema.ValidateJSON(json.RawMessage(invalid))
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("ValidateJSON() error = %v, want ValidationErrors", err)
+	}
+
+	got := make(map[string]bool, len(errs))
+	for _, e := range errs {
+		got[e.Path+" "+e.Keyword] = true
+	}
+	for _, want := range []string{
+		"/age minimum", "/name minLength", "/name pattern", "/email format", "/id format", "/site format",
+		"/created format", "/kind const", "/nick type", "/tags maxItems", "/tags/2 type", "/owner/name required",
+		"/value oneOf", "/limit anyOf", "/range maximum", "/extra additionalProperties",
+	} {
+		if !got[want] {
+			t.Errorf("missing violation %q in %v", want, errs)
+		}
+	}
+}
+
+func TestPropertyUnmarshalJSON(t *testing.T) {
+	raw := `{
+		"type": "object",
+		"properties": {
+			"a": {"type": ["string", "null"], "enum": ["x", "y"]},
+			"b": {"type": "integer", "enum": [1, 2]},
+			"c": {"type": ["string", "integer"]},
+			"d": {"$ref": "#/definitions/d"}
+		},
+		"additionalProperties": {"type": "boolean"},
+		"definitions": {"d": {"type": "number", "exclusiveMaximum": 1}}
+	}`
+
+	var schema Property
+	if err := json.Unmarshal([]byte(raw), &schema); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if a := schema.Properties["a"]; a.Type != String || !a.Nullable {
+		t.Errorf("a = %+v, want nullable string", a)
+	}
+	if b := schema.Properties["b"]; !reflect.DeepEqual(b.Enum, []string{"1", "2"}) {
+		t.Errorf("b.Enum = %v, want [1 2]", b.Enum)
+	}
+	if c := schema.Properties["c"]; len(c.AnyOf) != 2 {
+		t.Errorf("c.AnyOf = %v, want 2 branches", c.AnyOf)
+	}
+	if _, ok := schema.AdditionalProperties.(*Property); !ok {
+		t.Errorf("AdditionalProperties = %T, want *Property", schema.AdditionalProperties)
+	}
+
+	if err := schema.ValidateJSON(json.RawMessage(`{"a":null,"b":2,"c":3,"d":0.5,"e":true}`)); err != nil {
+		t.Errorf("ValidateJSON() unexpected error = %v", err)
+	}
+	if err := schema.ValidateJSON(json.RawMessage(`{"a":"z","b":3,"c":true,"d":1,"e":"no"}`)); err == nil {
+		t.Errorf("ValidateJSON() expected error")
+	}
+
+	b, err := json.Marshal(schema.Properties["a"])
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(b), `"type":["string","null"]`) {
+		t.Errorf("Marshal() = %s, want type array", b)
+	}
+}
+
+func TestVerifyAndUnmarshalDefault(t *testing.T) {
+	schema := Property{
+		Type: ObjectT,
+		Properties: map[string]*Property{
+			"name":  {Type: String},
+			"limit": {Type: Integer, Default: 10},
+		},
+		Required: []string{"name"},
+	}
+
+	v := &struct {
+		Name  string `json:"name"`
+		Limit int    `json:"limit"`
+	}{}
+	if err := verifySchemaAndUnmarshal(schema, []byte(`{"name":"a"}`), v); err != nil {
+		t.Fatalf("verifySchemaAndUnmarshal() error = %v", err)
+	}
+	if v.Limit != 10 {
+		t.Errorf("Limit = %d, want default 10", v.Limit)
+	}
+}
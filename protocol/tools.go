@@ -52,7 +52,11 @@ type Tool struct {
 	// Annotations provides additional hints about the tool's behavior
 	Annotations *ToolAnnotations `json:"annotations,omitempty"`
 
+	// RawInputSchema, when set, is sent as is in place of InputSchema,
+	// which then holds its parsed form (see Validate)
 	RawInputSchema json.RawMessage `json:"-"`
+
+	rawSchema *Property
 }
 
 /*func (t *Tool) GetName() string {
@@ -71,11 +75,8 @@ func (t *Tool) MarshalJSON() ([]byte, error) {
 		m["description"] = t.Description
 	}
 
-	// Determine which schema to use
+	// Determine which schema to use, the raw document keeps keywords InputSchema does not model
 	if t.RawInputSchema != nil {
-		if t.InputSchema.Type != "" {
-			return nil, fmt.Errorf("inputSchema field conflict")
-		}
 		m["inputSchema"] = t.RawInputSchema
 	} else {
 		// Use the structured InputSchema
@@ -90,6 +91,30 @@ func (t *Tool) MarshalJSON() ([]byte, error) {
 	return json.Marshal(m)
 }
 
+// Validate checks that the tool can be listed and called. RawInputSchema, when set, is parsed
+// with ParseInputSchema, and its parsed form then backs InputSchema and VerifyArguments.
+func (t *Tool) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("tool name is empty")
+	}
+	if t.RawInputSchema == nil || t.rawSchema != nil {
+		return nil
+	}
+
+	schema, err := ParseInputSchema(t.RawInputSchema)
+	if err != nil {
+		return fmt.Errorf("tool %s: %w", t.Name, err)
+	}
+	t.rawSchema = schema
+	t.InputSchema = InputSchema{
+		Type:       Object,
+		Properties: schema.Properties,
+		Required:   schema.Required,
+		Defs:       schema.Defs,
+	}
+	return nil
+}
+
 type InputSchemaType string
 
 const Object InputSchemaType = "object"
@@ -99,6 +124,38 @@ type InputSchema struct {
 	Type       InputSchemaType      `json:"type"`
 	Properties map[string]*Property `json:"properties,omitempty"`
 	Required   []string             `json:"required,omitempty"`
+	Defs       map[string]*Property `json:"$defs,omitempty"`
+}
+
+func (s *InputSchema) toProperty() Property {
+	return Property{
+		Type:       ObjectT,
+		Properties: s.Properties,
+		Required:   s.Required,
+		Defs:       s.Defs,
+	}
+}
+
+// VerifyArguments validates the call arguments against the tool's input schema, taken from
+// RawInputSchema when set and from InputSchema otherwise, and reports every violation as ValidationErrors.
+func (t *Tool) VerifyArguments(arguments json.RawMessage) error {
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+
+	if t.RawInputSchema == nil {
+		schema := t.InputSchema.toProperty()
+		return schema.ValidateJSON(arguments)
+	}
+
+	schema := t.rawSchema
+	if schema == nil {
+		var err error
+		if schema, err = ParseInputSchema(t.RawInputSchema); err != nil {
+			return err
+		}
+	}
+	return schema.ValidateJSON(arguments)
 }
 
 // CallToolRequest represents a request to call a specific tool
@@ -229,12 +286,28 @@ func NewTool(name string, description string, inputReqStruct interface{}) (*Tool
 	}, nil
 }
 
-func NewToolWithRawSchema(name, description string, schema json.RawMessage) *Tool {
-	return &Tool{
+// NewToolWithSchema create a tool whose input schema is loaded from provider,
+// the schema is parsed and checked here so that a broken document fails before the tool is registered.
+func NewToolWithSchema(name, description string, provider SchemaProvider) (*Tool, error) {
+	schema, err := provider.InputSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	tool := &Tool{
 		Name:           name,
 		Description:    description,
 		RawInputSchema: schema,
 	}
+	if err = tool.Validate(); err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// NewToolWithRawSchema create a tool from a raw schema document, which is parsed and checked here.
+func NewToolWithRawSchema(name, description string, schema json.RawMessage) (*Tool, error) {
+	return NewToolWithSchema(name, description, RawSchema(schema))
 }
 
 // NewListToolsRequest creates a new list tools request
@@ -116,6 +116,8 @@ func (server *Server) sendNotification4ResourceListChanges(ctx context.Context)
 	return pkg.JoinErrors(errList)
 }
 
+// SendNotification4ResourcesUpdated notifies every session subscribed to notify.URI,
+// directly or through a subscribed uri template or "*" prefix that matches it.
 func (server *Server) SendNotification4ResourcesUpdated(ctx context.Context, notify *protocol.ResourceUpdatedNotification) error {
 	if server.capabilities.Resources == nil || !server.capabilities.Resources.Subscribe {
 		return pkg.ErrServerNotSupport
@@ -123,7 +125,7 @@ func (server *Server) SendNotification4ResourcesUpdated(ctx context.Context, not
 
 	var errList []error
 	server.sessionManager.RangeSessions(func(sessionID string, s *session.State) bool {
-		if _, ok := s.GetSubscribedResources().Get(notify.URI); !ok {
+		if !server.isSubscribed(s, notify.URI) {
 			return true
 		}
 
@@ -136,6 +138,10 @@ func (server *Server) SendNotification4ResourcesUpdated(ctx context.Context, not
 }
 
 // Responsible for request and response assembly
+func (server *Server) sendNotification4Cancel(ctx context.Context, sessionID string, requestID protocol.RequestID, reason string) error {
+	return server.sendMsgWithNotification(ctx, sessionID, protocol.NotificationCancelled, protocol.NewCancelledNotification(requestID, reason))
+}
+
 func (server *Server) callClient(ctx context.Context, sessionID string, method protocol.Method, params protocol.ServerRequest) (json.RawMessage, error) {
 	session, ok := server.sessionManager.GetSession(sessionID)
 	if !ok {
@@ -153,6 +159,10 @@ func (server *Server) callClient(ctx context.Context, sessionID string, method p
 
 	select {
 	case <-ctx.Done():
+		// the shield keeps the send chan of ctx usable for the notification
+		if err := server.sendNotification4Cancel(pkg.NewCancelShieldContext(ctx), sessionID, requestID, ctx.Err().Error()); err != nil {
+			server.logger.Warnf("send cancellation notification fail: %v", err)
+		}
 		return nil, ctx.Err()
 	case response := <-respChan:
 		if err := response.Error; err != nil {
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// RegisterFunc registers fn as a tool whose input schema is derived from its signature.
+// Arguments are decoded into the parameters and the results are wrapped into a CallToolResult.
+//
+// fn may take a context.Context first, followed either by parameters named by paramNames in order,
+// or by a single struct describing the arguments like the request struct of protocol.NewTool.
+// It may return a value, an error, or both: a string becomes TextContent, a protocol.Content,
+// []protocol.Content or *protocol.CallToolResult is returned as is, any other value is sent as JSON text,
+// and a non-nil error is reported as a result with isError set.
+// eg:
+// server.RegisterFunc("add", "add two numbers", func(a, b int) int { return a + b }, "a", "b")
+func (server *Server) RegisterFunc(name, description string, fn interface{}, paramNames ...string) error {
+	tool, handler, err := newFuncTool(name, description, fn, paramNames)
+	if err != nil {
+		return err
+	}
+	return server.AddTool(tool, handler)
+}
+
+func newFuncTool(name, description string, fn interface{}, paramNames []string) (*protocol.Tool, ToolHandlerFunc, error) {
+	fnValue := reflect.ValueOf(fn)
+	if fnValue.Kind() != reflect.Func {
+		return nil, nil, fmt.Errorf("tool %s: %T is not a function", name, fn)
+	}
+	fnType := fnValue.Type()
+	if fnType.IsVariadic() {
+		return nil, nil, fmt.Errorf("tool %s: variadic functions are not supported", name)
+	}
+	if err := checkFuncResults(fnType); err != nil {
+		return nil, nil, fmt.Errorf("tool %s: %w", name, err)
+	}
+
+	withContext := fnType.NumIn() > 0 && fnType.In(0) == contextType
+	params := make([]reflect.Type, 0, fnType.NumIn())
+	for i := 0; i < fnType.NumIn(); i++ {
+		if i == 0 && withContext {
+			continue
+		}
+		params = append(params, fnType.In(i))
+	}
+
+	var (
+		tool   *protocol.Tool
+		decode func(json.RawMessage) ([]reflect.Value, error)
+	)
+	if len(paramNames) == 0 && len(params) == 1 && protocol.IsObjectStruct(params[0]) {
+		var err error
+		if tool, err = protocol.NewTool(name, description, reflect.Zero(params[0]).Interface()); err != nil {
+			return nil, nil, err
+		}
+		decode = func(raw json.RawMessage) ([]reflect.Value, error) {
+			arg := reflect.New(params[0])
+			if err := protocol.VerifyAndUnmarshal(raw, arg.Interface()); err != nil {
+				return nil, err
+			}
+			return []reflect.Value{arg.Elem()}, nil
+		}
+	} else {
+		if len(paramNames) != len(params) {
+			return nil, nil, fmt.Errorf("tool %s: got %d parameter names for %d parameters", name, len(paramNames), len(params))
+		}
+		schema, err := protocol.GenerateSchemaFromParams(paramNames, params)
+		if err != nil {
+			return nil, nil, fmt.Errorf("tool %s: %w", name, err)
+		}
+		tool = &protocol.Tool{Name: name, Description: description, InputSchema: *schema}
+		decode = func(raw json.RawMessage) ([]reflect.Value, error) {
+			if err := tool.VerifyArguments(raw); err != nil {
+				return nil, err
+			}
+			var arguments map[string]json.RawMessage
+			if err := pkg.JSONUnmarshal(raw, &arguments); err != nil {
+				return nil, err
+			}
+			values := make([]reflect.Value, len(params))
+			for i, param := range params {
+				arg := reflect.New(param)
+				if rawArg, ok := arguments[paramNames[i]]; ok {
+					if err := pkg.JSONUnmarshal(rawArg, arg.Interface()); err != nil {
+						return nil, fmt.Errorf("invalid argument %s: %w", paramNames[i], err)
+					}
+				}
+				values[i] = arg.Elem()
+			}
+			return values, nil
+		}
+	}
+
+	handler := func(ctx context.Context, request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+		raw := request.RawArguments
+		if len(raw) == 0 {
+			raw = json.RawMessage("{}")
+			if request.Arguments != nil {
+				var err error
+				if raw, err = json.Marshal(request.Arguments); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		args, err := decode(raw)
+		if err != nil {
+			return nil, err
+		}
+		if withContext {
+			args = append([]reflect.Value{reflect.ValueOf(ctx)}, args...)
+		}
+		return wrapFuncResults(fnValue.Call(args))
+	}
+	return tool, handler, nil
+}
+
+func checkFuncResults(fnType reflect.Type) error {
+	switch fnType.NumOut() {
+	case 0, 1:
+		return nil
+	case 2:
+		if fnType.Out(1) != errorType {
+			return fmt.Errorf("the second result must be an error, got %v", fnType.Out(1))
+		}
+		return nil
+	default:
+		return fmt.Errorf("expected at most 2 results, got %d", fnType.NumOut())
+	}
+}
+
+func wrapFuncResults(results []reflect.Value) (*protocol.CallToolResult, error) {
+	if n := len(results); n > 0 && results[n-1].Type() == errorType {
+		if err, _ := results[n-1].Interface().(error); err != nil {
+			return protocol.NewCallToolResult([]protocol.Content{newTextContent(err.Error())}, true), nil
+		}
+		results = results[:n-1]
+	}
+	if len(results) == 0 {
+		return protocol.NewCallToolResult([]protocol.Content{}, false), nil
+	}
+
+	switch result := results[0].Interface().(type) {
+	case *protocol.CallToolResult:
+		if result == nil {
+			return protocol.NewCallToolResult([]protocol.Content{}, false), nil
+		}
+		return result, nil
+	case []protocol.Content:
+		return protocol.NewCallToolResult(result, false), nil
+	case protocol.Content:
+		return protocol.NewCallToolResult([]protocol.Content{result}, false), nil
+	case string:
+		return protocol.NewCallToolResult([]protocol.Content{newTextContent(result)}, false), nil
+	default:
+		text, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("marshal tool result: %w", err)
+		}
+		return protocol.NewCallToolResult([]protocol.Content{newTextContent(string(text))}, false), nil
+	}
+}
+
+func newTextContent(text string) *protocol.TextContent {
+	return &protocol.TextContent{Type: "text", Text: text}
+}
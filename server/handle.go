@@ -158,32 +158,34 @@ func (server *Server) handleRequestWithReadResource(ctx context.Context, rawPara
 		return nil, err
 	}
 
+	// a static resource takes precedence over any template matching its uri
 	var handler ResourceHandlerFunc
 	if entry, ok := server.resources.Load(request.URI); ok {
 		handler = entry.handler
-	}
-
-	server.resourceTemplates.Range(func(_ string, entry *resourceTemplateEntry) bool {
-		if !matchesTemplate(request.URI, entry.resourceTemplate.URITemplateParsed) {
-			return true
-		}
+	} else if entry, arguments := server.matchResourceTemplate(request.URI); entry != nil {
 		handler = entry.handler
-		matchedVars := entry.resourceTemplate.URITemplateParsed.Match(request.URI)
-		request.Arguments = make(map[string]interface{})
-		for name, value := range matchedVars {
-			request.Arguments[name] = value.V
-		}
-		return false
-	})
+		request.Arguments = arguments
+	}
 
 	if handler == nil {
 		return nil, fmt.Errorf("missing resource, resourceName=%s", request.URI)
 	}
-	return handler(ctx, request)
+
+	result, err := handler(ctx, request)
+	if err != nil || result == nil || result.IsNotModified() {
+		return result, err
+	}
+	if server.resourceContentVersions && result.GetVersion() == "" {
+		result.SetVersion(protocol.ResourceContentsVersion(result.Contents))
+	}
+	if version := request.GetIfNoneMatch(); version != "" && version == result.GetVersion() {
+		return protocol.NewNotModifiedReadResourceResult(version), nil
+	}
+	return result, nil
 }
 
-func (server *Server) handleRequestWithSubscribeResourceChange(sessionID string, rawParams json.RawMessage) (*protocol.SubscribeResult, error) {
-	if server.capabilities.Resources == nil && !server.capabilities.Resources.Subscribe {
+func (server *Server) handleRequestWithSubscribeResourceChange(ctx context.Context, sessionID string, rawParams json.RawMessage) (*protocol.SubscribeResult, error) {
+	if server.capabilities.Resources == nil || !server.capabilities.Resources.Subscribe {
 		return nil, pkg.ErrServerNotSupport
 	}
 
@@ -196,12 +198,35 @@ func (server *Server) handleRequestWithSubscribeResourceChange(sessionID string,
 	if !ok {
 		return nil, pkg.ErrLackSession
 	}
-	s.GetSubscribedResources().Set(request.URI, struct{}{})
+
+	subscribed := s.GetSubscribedResources()
+	if subscribed.Has(request.URI) {
+		return protocol.NewSubscribeResult(), nil
+	}
+
+	hooks, ok := server.lookupSubscription(request.URI)
+	if !ok {
+		return nil, fmt.Errorf("%w: uri=%s", pkg.ErrResourceNotFound, request.URI)
+	}
+	if err := server.acquireSubscription(ctx, request.URI, hooks); err != nil {
+		return nil, fmt.Errorf("subscribe %s: %w", request.URI, err)
+	}
+	if !subscribed.SetIfAbsent(request.URI, struct{}{}) {
+		// a concurrent request of the same session subscribed first
+		server.releaseSubscription(ctx, request.URI)
+		return protocol.NewSubscribeResult(), nil
+	}
+	if s.IsClosed() {
+		if _, ok = subscribed.Pop(request.URI); ok {
+			server.releaseSubscription(ctx, request.URI)
+		}
+		return nil, pkg.ErrSessionClosed
+	}
 	return protocol.NewSubscribeResult(), nil
 }
 
 func (server *Server) handleRequestWithUnSubscribeResourceChange(sessionID string, rawParams json.RawMessage) (*protocol.UnsubscribeResult, error) {
-	if server.capabilities.Resources == nil && !server.capabilities.Resources.Subscribe {
+	if server.capabilities.Resources == nil || !server.capabilities.Resources.Subscribe {
 		return nil, pkg.ErrServerNotSupport
 	}
 
@@ -214,7 +239,9 @@ func (server *Server) handleRequestWithUnSubscribeResourceChange(sessionID strin
 	if !ok {
 		return nil, pkg.ErrLackSession
 	}
-	s.GetSubscribedResources().Remove(request.URI)
+	if _, ok = s.GetSubscribedResources().Pop(request.URI); ok {
+		server.releaseSubscription(context.Background(), request.URI)
+	}
 	return protocol.NewUnsubscribeResult(), nil
 }
 
@@ -259,8 +286,19 @@ func (server *Server) handleRequestWithCallTool(ctx context.Context, rawParams j
 	if !ok {
 		return nil, fmt.Errorf("missing tool, toolName=%s", request.Name)
 	}
+	// handlers of tools generated from structs verify their arguments while decoding them,
+	// raw schemas have no struct to decode into
+	if entry.tool.RawInputSchema != nil {
+		if err := entry.tool.VerifyArguments(request.RawArguments); err != nil {
+			return nil, err
+		}
+	}
 
-	return entry.handler(ctx, request)
+	result, err := entry.handler(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return server.limitToolResult(ctx, request, result)
 }
 
 func (server *Server) handleNotifyWithInitialized(sessionID string, rawParams json.RawMessage) error {
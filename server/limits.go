@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+)
+
+const (
+	defaultMaxJSONDepth   = 128
+	defaultMaxBatchLength = 100
+)
+
+// WithMaxJSONDepth refuses received messages nested deeper than depth, 128 by default, 0 for no limit.
+func WithMaxJSONDepth(depth int) Option {
+	return func(s *Server) {
+		s.maxJSONDepth = depth
+	}
+}
+
+// WithMaxBatchLength refuses received batches of more than length messages, 100 by default, 0 for no limit.
+func WithMaxBatchLength(length int) Option {
+	return func(s *Server) {
+		s.maxBatchLength = length
+	}
+}
+
+// WithMaxResponseSize limits the size of the responses sent to clients, a larger one is replaced by an error.
+// Tool results are given to the OversizedToolResultHandler first, if any. No limit by default.
+func WithMaxResponseSize(size int) Option {
+	return func(s *Server) {
+		s.maxResponseSize = size
+	}
+}
+
+// OversizedToolResultHandler is given a tool result over the maximum response size, it returns the result to
+// send instead, truncated or pointing to where the content can be fetched for example.
+type OversizedToolResultHandler func(ctx context.Context, req *protocol.CallToolRequest,
+	result *protocol.CallToolResult, maxSize int) (*protocol.CallToolResult, error)
+
+// WithOversizedToolResultHandler sets the handler of tool results over the size set with WithMaxResponseSize.
+func WithOversizedToolResultHandler(handler OversizedToolResultHandler) Option {
+	return func(s *Server) {
+		s.oversizedToolResultHandler = handler
+	}
+}
+
+// TruncateToolResult is an OversizedToolResultHandler that cuts the text contents of a result to fit
+// maxSize, drops its other contents and notes the truncation at the end.
+func TruncateToolResult(_ context.Context, _ *protocol.CallToolRequest,
+	result *protocol.CallToolResult, maxSize int,
+) (*protocol.CallToolResult, error) { //nolint:whitespace
+	note := &protocol.TextContent{Type: "text", Text: "[truncated: the result exceeded the maximum response size]"}
+
+	truncated := &protocol.CallToolResult{IsError: result.IsError}
+	budget := maxSize - len(note.Text)
+	for _, content := range result.Content {
+		text, ok := content.(*protocol.TextContent)
+		if !ok {
+			continue
+		}
+		if budget <= 0 {
+			break
+		}
+		cut := truncateUTF8(text.Text, budget)
+		budget -= len(cut)
+		truncated.Content = append(truncated.Content, &protocol.TextContent{Annotated: text.Annotated, Type: "text", Text: cut})
+	}
+	truncated.Content = append(truncated.Content, note)
+
+	// escaping and the fields of the contents take room too, shorten the last text until the result fits
+	for len(truncated.Content) > 1 {
+		size, err := jsonSize(truncated)
+		if err != nil {
+			return nil, err
+		}
+		over := size - maxSize
+		if over <= 0 {
+			break
+		}
+		last := truncated.Content[len(truncated.Content)-2].(*protocol.TextContent)
+		if over >= len(last.Text) {
+			truncated.Content = append(truncated.Content[:len(truncated.Content)-2], note)
+			continue
+		}
+		last.Text = truncateUTF8(last.Text, len(last.Text)-over)
+	}
+	return truncated, nil
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func jsonSize(v interface{}) (int, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// responseEnvelopeSize is the room left for the JSON-RPC fields around a result
+const responseEnvelopeSize = 128
+
+// limitToolResult hands a result over the maximum response size to the OversizedToolResultHandler
+func (server *Server) limitToolResult(ctx context.Context, req *protocol.CallToolRequest,
+	result *protocol.CallToolResult,
+) (*protocol.CallToolResult, error) { //nolint:whitespace
+	if server.maxResponseSize <= 0 || server.oversizedToolResultHandler == nil || result == nil {
+		return result, nil
+	}
+	maxSize := server.maxResponseSize - responseEnvelopeSize
+	size, err := jsonSize(result)
+	if err != nil || size <= maxSize {
+		return result, nil
+	}
+	return server.oversizedToolResultHandler(ctx, req, result, maxSize)
+}
+
+// limitResponse replaces a response over the maximum size by an error
+func (server *Server) limitResponse(id protocol.RequestID, message []byte) []byte {
+	if server.maxResponseSize <= 0 || len(message) <= server.maxResponseSize {
+		return message
+	}
+	server.logger.Warnf("response of request id=%v is %d bytes, over the limit of %d", id, len(message), server.maxResponseSize)
+
+	err := fmt.Errorf("%w: response of %d bytes, limit %d", pkg.ErrMessageTooLarge, len(message), server.maxResponseSize)
+	resp, e := json.Marshal(protocol.NewJSONRPCErrorResponse(id, protocol.InternalError, err.Error()))
+	if e != nil {
+		return message
+	}
+	return resp
+}
+
+// checkJSONLimits refuses a received message over the depth or batch length limits,
+// answering it with an error when it is a request.
+func (server *Server) checkJSONLimits(msg []byte) (<-chan []byte, error) {
+	err := pkg.CheckJSONLimits(msg, server.maxJSONDepth, server.maxBatchLength)
+	if err == nil {
+		return nil, nil
+	}
+	id := gjson.GetBytes(msg, "id")
+	if !id.Exists() || !gjson.GetBytes(msg, "method").Exists() {
+		return nil, err
+	}
+	resp, e := json.Marshal(protocol.NewJSONRPCErrorResponse(id.Value(), protocol.InvalidRequest, err.Error()))
+	if e != nil {
+		return nil, err
+	}
+	ch := make(chan []byte, 1)
+	ch <- resp
+	close(ch)
+	return ch, nil
+}
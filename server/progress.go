@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+)
+
+const defaultProgressInterval = 100 * time.Millisecond
+
+type ProgressOption func(*ProgressReporter)
+
+// WithProgressInterval sets the minimum time between two notifications, updates in between are coalesced.
+// 0 sends every update.
+func WithProgressInterval(interval time.Duration) ProgressOption {
+	return func(r *ProgressReporter) {
+		r.interval = interval
+	}
+}
+
+// WithProgressTotal sets the total known upfront, see ProgressReporter.SetTotal.
+func WithProgressTotal(total float64) ProgressOption {
+	return func(r *ProgressReporter) {
+		r.total = total
+	}
+}
+
+// ProgressReporter reports the progress of the request being handled. Updates are rate limited:
+// an update arriving less than the interval after the last notification is held back and sent
+// once the interval has passed, or by Done. It does nothing if the request carries no progress token.
+// A ProgressReporter must not be used after the handler has returned.
+type ProgressReporter struct {
+	ctx    context.Context
+	server *Server
+	active bool
+
+	interval time.Duration
+
+	mu       sync.Mutex
+	progress float64
+	total    float64
+	message  string
+	pending  bool
+	lastSent time.Time
+	// flush sends the held back update at the end of the interval
+	flush *time.Timer
+	done  bool
+}
+
+// NewProgressReporter returns a reporter for the request handled with ctx.
+func (server *Server) NewProgressReporter(ctx context.Context, opts ...ProgressOption) *ProgressReporter {
+	_, err := getProgressTokenFromCtx(ctx)
+	r := &ProgressReporter{
+		ctx:      ctx,
+		server:   server,
+		active:   err == nil,
+		interval: defaultProgressInterval,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Advance adds n to the progress, which only ever increases.
+func (r *ProgressReporter) Advance(n float64) {
+	if n <= 0 {
+		return
+	}
+	r.update(func() {
+		r.progress += n
+	})
+}
+
+// AdvanceWithMessage adds n to the progress and replaces the message.
+func (r *ProgressReporter) AdvanceWithMessage(n float64, message string) {
+	r.update(func() {
+		if n > 0 {
+			r.progress += n
+		}
+		r.message = message
+	})
+}
+
+// SetTotal sets the total the progress is heading to, 0 when unknown.
+func (r *ProgressReporter) SetTotal(total float64) {
+	r.update(func() {
+		r.total = total
+	})
+}
+
+// SetMessage replaces the message sent with the progress.
+func (r *ProgressReporter) SetMessage(message string) {
+	r.update(func() {
+		r.message = message
+	})
+}
+
+// Done sends the update held back by the rate limit, if any. Call it before the handler returns.
+func (r *ProgressReporter) Done() {
+	if !r.active {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.done = true
+	if r.flush != nil {
+		r.flush.Stop()
+		r.flush = nil
+	}
+	if r.pending {
+		r.send()
+	}
+}
+
+func (r *ProgressReporter) update(apply func()) {
+	if !r.active {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	apply()
+	r.pending = true
+	if r.done {
+		// the handler has returned, the update is dropped with the request
+		return
+	}
+	wait := r.interval - time.Since(r.lastSent)
+	if wait <= 0 {
+		r.send()
+		return
+	}
+	if r.flush == nil {
+		r.flush = time.AfterFunc(wait, r.flushPending)
+	}
+}
+
+func (r *ProgressReporter) flushPending() {
+	defer pkg.Recover()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.flush = nil
+	if !r.pending || r.done {
+		return
+	}
+	// an update sent since the timer was set starts a new interval
+	if wait := r.interval - time.Since(r.lastSent); wait > 0 {
+		r.flush = time.AfterFunc(wait, r.flushPending)
+		return
+	}
+	r.send()
+}
+
+func (r *ProgressReporter) send() {
+	r.pending = false
+	r.lastSent = time.Now()
+	if err := r.server.SendProgressNotification(r.ctx, protocol.NewProgressNotification(r.progress, r.total, r.message)); err != nil {
+		r.server.logger.Warnf("send progress fail: %v", err)
+	}
+}
+
+// SamplingWithProgressChan is Sampling for clients reporting the progress of the request, eg. while the user
+// reviews it. progressCh receives the notifications and is closed when the method returns.
+// Sends to progressCh never block: while the reader is behind only the latest notification is kept for it.
+func (server *Server) SamplingWithProgressChan(ctx context.Context, request *protocol.CreateMessageRequest,
+	progressCh chan<- *protocol.ProgressNotification) (*protocol.CreateMessageResult, error) { //nolint:gofumpt
+
+	progressToken := uuid.NewString()
+	listener := pkg.NewLatestSender(progressCh)
+	server.progressChanRW.Lock()
+	server.progressToken2listener[progressToken] = listener
+	server.progressChanRW.Unlock()
+	defer func() {
+		server.progressChanRW.Lock()
+		delete(server.progressToken2listener, progressToken)
+		server.progressChanRW.Unlock()
+		listener.Close()
+	}()
+
+	// the caller's request and its meta are left untouched, they may be shared or reused
+	meta := make(map[string]interface{}, len(request.Meta)+1)
+	for k, v := range request.Meta {
+		meta[k] = v
+	}
+	meta[protocol.ProgressTokenKey] = progressToken
+	withMeta := *request
+	withMeta.Meta = meta
+
+	return server.Sampling(ctx, &withMeta)
+}
+
+func (server *Server) handleNotifyWithProgress(rawParams json.RawMessage) error {
+	notify := &protocol.ProgressNotification{}
+	if len(rawParams) > 0 {
+		if err := pkg.JSONUnmarshal(rawParams, notify); err != nil {
+			return err
+		}
+	}
+	server.progressChanRW.RLock()
+	listener, ok := server.progressToken2listener[fmt.Sprint(notify.ProgressToken)]
+	server.progressChanRW.RUnlock()
+	if !ok {
+		return fmt.Errorf("progress token not found")
+	}
+	listener.Send(notify)
+	return nil
+}
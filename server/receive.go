@@ -20,6 +20,10 @@ func (server *Server) receive(ctx context.Context, sessionID string, msg []byte)
 		return nil, pkg.ErrLackSession
 	}
 
+	if ch, err := server.checkJSONLimits(msg); ch != nil || err != nil {
+		return ch, err
+	}
+
 	if !gjson.GetBytes(msg, "id").Exists() {
 		notify := &protocol.JSONRPCNotification{}
 		if err := pkg.JSONUnmarshal(msg, &notify); err != nil {
@@ -100,7 +104,7 @@ func (server *Server) receive(ctx context.Context, sessionID string, msg []byte)
 			server.logger.Errorf("receive json marshal response:%+v error: %s", resp, err.Error())
 			return
 		}
-		ch <- message
+		ch <- server.limitResponse(req.ID, message)
 	}(pkg.NewCancelShieldContext(ctx))
 	return ch, nil
 }
@@ -135,7 +139,7 @@ func (server *Server) receiveRequest(ctx context.Context, sessionID string, requ
 	case protocol.ResourcesRead:
 		result, err = server.handleRequestWithReadResource(ctx, request.RawParams)
 	case protocol.ResourcesSubscribe:
-		result, err = server.handleRequestWithSubscribeResourceChange(sessionID, request.RawParams)
+		result, err = server.handleRequestWithSubscribeResourceChange(ctx, sessionID, request.RawParams)
 	case protocol.ResourcesUnsubscribe:
 		result, err = server.handleRequestWithUnSubscribeResourceChange(sessionID, request.RawParams)
 	case protocol.ToolsList:
@@ -147,7 +151,10 @@ func (server *Server) receiveRequest(ctx context.Context, sessionID string, requ
 	}
 
 	if err != nil {
-		var code int
+		var (
+			code             int
+			validationErrors protocol.ValidationErrors
+		)
 		switch {
 		case errors.Is(err, pkg.ErrMethodNotSupport):
 			code = protocol.MethodNotFound
@@ -155,10 +162,19 @@ func (server *Server) receiveRequest(ctx context.Context, sessionID string, requ
 			code = protocol.InvalidRequest
 		case errors.Is(err, pkg.ErrJSONUnmarshal):
 			code = protocol.ParseError
+		case errors.As(err, &validationErrors):
+			code = protocol.InvalidParams
+		case errors.Is(err, pkg.ErrResourceNotFound):
+			code = protocol.ResourceNotFound
 		default:
 			code = protocol.InternalError
 		}
-		return protocol.NewJSONRPCErrorResponse(request.ID, code, err.Error())
+		response := protocol.NewJSONRPCErrorResponse(request.ID, code, err.Error())
+		if validationErrors != nil {
+			// the JSON pointers of the offending arguments
+			response.Error.Data = validationErrors
+		}
+		return response
 	}
 	return protocol.NewJSONRPCSuccessResponse(request.ID, result)
 }
@@ -177,6 +193,8 @@ func (server *Server) receiveNotify(sessionID string, notify *protocol.JSONRPCNo
 		return server.handleNotifyWithInitialized(sessionID, notify.RawParams)
 	case protocol.NotificationCancelled:
 		return server.handleNotifyWithCancelled(sessionID, notify.RawParams)
+	case protocol.NotificationProgress:
+		return server.handleNotifyWithProgress(notify.RawParams)
 	default:
 		return fmt.Errorf("%w: method=%s", pkg.ErrMethodNotSupport, notify.Method)
 	}
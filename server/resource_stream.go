@@ -0,0 +1,193 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+)
+
+const defaultResourceChunkSize = 1 << 20
+
+// WithResourceChunkSize limits how many bytes of a streamed resource one resources/read returns.
+func WithResourceChunkSize(size int64) Option {
+	return func(s *Server) {
+		s.resourceChunkSize = size
+	}
+}
+
+// ResourceStream is resource content served in ranges, see StreamResourceHandler.
+type ResourceStream struct {
+	// Reader supplies the content. When it implements io.ReaderAt or io.Seeker the requested offset
+	// is reached without reading the content before it, when it implements io.Closer it is closed after the read.
+	Reader io.Reader
+	// Size is the total content size in bytes, or -1 if unknown
+	Size     int64
+	MimeType string
+	// Text sends ranges as TextResourceContents cut at UTF-8 character boundaries, otherwise as BlobResourceContents
+	Text bool
+}
+
+type ResourceStreamHandlerFunc func(context.Context, *protocol.ReadResourceRequest) (*ResourceStream, error)
+
+// RegisterResourceStream registers a resource whose content is served in ranges, see StreamResourceHandler.
+func (server *Server) RegisterResourceStream(resource *protocol.Resource, handler ResourceStreamHandlerFunc, opts ...ResourceOption) {
+	server.RegisterResource(resource, server.StreamResourceHandler(handler), opts...)
+}
+
+// StreamResourceHandler adapts handler to a ResourceHandlerFunc, eg. for RegisterResourceTemplate, that returns
+// at most the chunk size (see WithResourceChunkSize) per read, so large content is never held in memory whole.
+// Clients ask for a range with protocol.ResourceRange under _meta of the request and the result's _meta tells
+// where the content continues; client.ReadResourceStream reads a resource this way until the end.
+// Progress notifications are sent while a range is read if the request carries a progress token.
+func (server *Server) StreamResourceHandler(handler ResourceStreamHandlerFunc) ResourceHandlerFunc {
+	return func(ctx context.Context, request *protocol.ReadResourceRequest) (*protocol.ReadResourceResult, error) {
+		requested, ok := protocol.GetResourceRange(request.Meta)
+		if !ok {
+			requested = &protocol.ResourceRange{}
+		}
+		if requested.Offset < 0 || requested.Length < 0 {
+			return nil, fmt.Errorf("invalid resource range, offset=%d length=%d", requested.Offset, requested.Length)
+		}
+
+		stream, err := handler(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		if stream == nil || stream.Reader == nil {
+			return nil, fmt.Errorf("resource stream handler returned no stream for %s", request.URI)
+		}
+		if closer, ok := stream.Reader.(io.Closer); ok {
+			defer closer.Close()
+		}
+
+		length := server.resourceChunkSize
+		if requested.Length > 0 && requested.Length < length {
+			length = requested.Length
+		}
+		if stream.Text && length < utf8.UTFMax {
+			// room for at least one character
+			length = utf8.UTFMax
+		}
+		content, eof, err := server.readResourceRange(ctx, stream, requested.Offset, length)
+		if err != nil {
+			return nil, err
+		}
+
+		returned := &protocol.ResourceRange{Offset: requested.Offset, Length: int64(len(content)), EOF: eof}
+		switch {
+		case stream.Size >= 0:
+			returned.Total = stream.Size
+		case eof:
+			returned.Total = requested.Offset + int64(len(content))
+		}
+
+		var contents protocol.ResourceContents
+		if stream.Text {
+			contents = &protocol.TextResourceContents{URI: request.URI, MimeType: stream.MimeType, Text: string(content)}
+		} else {
+			contents = &protocol.BlobResourceContents{URI: request.URI, MimeType: stream.MimeType, Blob: content}
+		}
+		result := protocol.NewReadResourceResult([]protocol.ResourceContents{contents})
+		result.Meta = map[string]interface{}{protocol.ResourceRangeKey: returned}
+		return result, nil
+	}
+}
+
+// readResourceRange reads at most length bytes from offset. One more byte is read when
+// the size is unknown, to tell whether the range ends the content.
+func (server *Server) readResourceRange(ctx context.Context, stream *ResourceStream, offset, length int64) ([]byte, bool, error) {
+	reader := stream.Reader
+	switch r := reader.(type) {
+	case io.ReaderAt:
+		reader = io.NewSectionReader(r, offset, length+1)
+	case io.Seeker:
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return nil, false, err
+		}
+	default:
+		if _, err := io.CopyN(io.Discard, reader, offset); err != nil && err != io.EOF {
+			return nil, false, err
+		}
+	}
+
+	want := length + 1
+	if stream.Size >= 0 {
+		if want = stream.Size - offset; want > length {
+			want = length
+		}
+		if want < 0 {
+			want = 0
+		}
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, want))
+	w := &progressWriter{ctx: ctx, server: server, buf: buf, offset: offset, total: stream.Size}
+	if _, err := io.CopyBuffer(w, io.LimitReader(reader, want), make([]byte, 64<<10)); err != nil {
+		return nil, false, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	content := buf.Bytes()
+	var eof bool
+	if stream.Size >= 0 {
+		eof = offset+int64(len(content)) >= stream.Size
+	} else {
+		eof = int64(len(content)) <= length
+		if !eof {
+			content = content[:length]
+		}
+	}
+
+	if stream.Text && !eof {
+		content = trimIncompleteRune(content)
+	}
+	return content, eof, nil
+}
+
+// trimIncompleteRune drops a UTF-8 character cut by the end of the range, it is sent with the next range
+func trimIncompleteRune(content []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(content); i++ {
+		if !utf8.RuneStart(content[len(content)-i]) {
+			continue
+		}
+		if !utf8.FullRune(content[len(content)-i:]) {
+			return content[:len(content)-i]
+		}
+		break
+	}
+	return content
+}
+
+// progressWriter collects a range and reports the bytes read so far as progress
+type progressWriter struct {
+	ctx    context.Context
+	server *Server
+	buf    *bytes.Buffer
+	offset int64
+	total  int64
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, _ := w.buf.Write(p)
+
+	if _, err := getProgressTokenFromCtx(w.ctx); err == nil {
+		total := float64(0)
+		if w.total >= 0 {
+			total = float64(w.total)
+		}
+		notify := protocol.NewProgressNotification(float64(w.offset)+float64(w.buf.Len()), total, "")
+		if err = w.server.SendProgressNotification(w.ctx, notify); err != nil {
+			w.server.logger.Warnf("send resource read progress fail: %v", err)
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+)
+
+// templateSpecificity ranks resource templates matching the same uri, see moreSpecific
+type templateSpecificity struct {
+	// literals is the number of characters outside of expressions
+	literals int
+	// multiSegment counts the expressions that may match "/", ie. reserved and fragment expansions and explode modifiers
+	multiSegment int
+	variables    int
+}
+
+// parseTemplateSpecificity ranks uriTemplate and returns its shape: the template with variable names
+// erased. Templates of the same shape match exactly the same uris, so they conflict.
+func parseTemplateSpecificity(uriTemplate string) (templateSpecificity, string) {
+	var (
+		spec  templateSpecificity
+		shape strings.Builder
+	)
+	for {
+		start := strings.IndexByte(uriTemplate, '{')
+		end := strings.IndexByte(uriTemplate, '}')
+		if start < 0 || end < start {
+			spec.literals += len(uriTemplate)
+			shape.WriteString(uriTemplate)
+			return spec, shape.String()
+		}
+		spec.literals += start
+		shape.WriteString(uriTemplate[:start])
+
+		expression := uriTemplate[start+1 : end]
+		var operator string
+		if expression != "" && strings.ContainsAny(expression[:1], "+#./;?&") {
+			operator = expression[:1]
+		}
+		explode := strings.Contains(expression, "*")
+		if operator == "+" || operator == "#" || explode {
+			spec.multiSegment++
+		}
+		specs := strings.Split(strings.TrimPrefix(expression, operator), ",")
+		spec.variables += len(specs)
+
+		shape.WriteString("{" + operator)
+		for i, varspec := range specs {
+			if i > 0 {
+				shape.WriteByte(',')
+			}
+			// keep the modifiers, they change what matches
+			if j := strings.IndexAny(varspec, ":*"); j >= 0 {
+				shape.WriteString(varspec[j:])
+			}
+		}
+		shape.WriteByte('}')
+		uriTemplate = uriTemplate[end+1:]
+	}
+}
+
+// moreSpecific reports whether a takes precedence over b when both match a uri: the template with more
+// literal characters wins, then the one with fewer expressions spanning segments, then fewer variables.
+// Remaining ties are broken by the template string so that the choice never depends on map order.
+func moreSpecific(a, b *resourceTemplateEntry) bool {
+	switch {
+	case a.specificity.literals != b.specificity.literals:
+		return a.specificity.literals > b.specificity.literals
+	case a.specificity.multiSegment != b.specificity.multiSegment:
+		return a.specificity.multiSegment < b.specificity.multiSegment
+	case a.specificity.variables != b.specificity.variables:
+		return a.specificity.variables < b.specificity.variables
+	default:
+		return a.resourceTemplate.URITemplate < b.resourceTemplate.URITemplate
+	}
+}
+
+// matchResourceTemplate returns the most specific template matching uri and the typed values of its variables.
+func (server *Server) matchResourceTemplate(uri string) (*resourceTemplateEntry, map[string]interface{}) {
+	var (
+		best      *resourceTemplateEntry
+		arguments map[string]interface{}
+	)
+	server.resourceTemplates.Range(func(_ string, entry *resourceTemplateEntry) bool {
+		if best != nil && !moreSpecific(entry, best) {
+			return true
+		}
+		if args, ok := entry.resourceTemplate.MatchArguments(uri); ok {
+			best, arguments = entry, args
+		}
+		return true
+	})
+	return best, arguments
+}
+
+// checkTemplateConflict rejects a template matching the same uris as another registered one,
+// re-registering the same template string replaces it.
+func (server *Server) checkTemplateConflict(entry *resourceTemplateEntry) error {
+	var conflict string
+	server.resourceTemplates.Range(func(uriTemplate string, registered *resourceTemplateEntry) bool {
+		if uriTemplate != entry.resourceTemplate.URITemplate && registered.shape == entry.shape {
+			conflict = uriTemplate
+			return false
+		}
+		return true
+	})
+	if conflict != "" {
+		return fmt.Errorf("%w: uri template %s matches the same uris as %s", pkg.ErrResourceTemplateConflict, entry.resourceTemplate.URITemplate, conflict)
+	}
+	return nil
+}
@@ -0,0 +1,372 @@
+// Package fs exposes the files of a directory or an fs.FS as MCP resources.
+//
+// Every file is listed as a resource with a file:/// uri, and the file:///{+path} template
+// serves files created after registration. Changes are detected by polling: modified or deleted
+// files are announced to subscribed sessions with notifications/resources/updated, and files that
+// appear or disappear update the resource list, which sends notifications/resources/list_changed.
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"github.com/ThinkInAIXYZ/go-mcp/server"
+)
+
+const (
+	uriPrefix   = "file:///"
+	uriTemplate = "file:///{+path}"
+)
+
+type Option func(*Provider)
+
+// WithPollInterval sets how often the files are checked for changes, 0 disables change detection.
+func WithPollInterval(interval time.Duration) Option {
+	return func(p *Provider) {
+		p.pollInterval = interval
+	}
+}
+
+// WithMaxFileSize limits the size of a file that can be read, larger files are still listed.
+func WithMaxFileSize(size int64) Option {
+	return func(p *Provider) {
+		p.maxFileSize = size
+	}
+}
+
+func WithLogger(logger pkg.Logger) Option {
+	return func(p *Provider) {
+		p.logger = logger
+	}
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+type Provider struct {
+	fsys iofs.FS
+	// root is the resolved directory of NewDir, symlinks must not lead outside of it
+	root string
+
+	server *server.Server
+
+	pollInterval time.Duration
+	maxFileSize  int64
+	logger       pkg.Logger
+
+	mu    sync.Mutex
+	files map[string]fileState
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// New exposes the files of fsys, paths are checked with fs.ValidPath so uris cannot leave fsys.
+func New(fsys iofs.FS, opts ...Option) *Provider {
+	p := &Provider{
+		fsys:         fsys,
+		pollInterval: 2 * time.Second,
+		maxFileSize:  10 << 20,
+		logger:       pkg.DefaultLogger,
+		files:        make(map[string]fileState),
+		stop:         make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// NewDir exposes the files under dir. Besides rejecting ".." in uris,
+// reads through symlinks pointing outside dir are refused.
+func NewDir(dir string, opts ...Option) (*Provider, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	p := New(os.DirFS(root), opts...)
+	p.root = root
+	return p, nil
+}
+
+// Register lists the files as resources of s, registers the file:///{+path} template
+// and starts watching for changes until Close is called.
+func (p *Provider) Register(s *server.Server) error {
+	files, err := p.scan()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.server = s
+	p.files = files
+	p.mu.Unlock()
+
+	s.UpdateResources(p.resources(sortedPaths(files), files), p.read, nil)
+	if err = s.RegisterResourceTemplate(&protocol.ResourceTemplate{
+		Name:        "file",
+		URITemplate: uriTemplate,
+		Description: "A file, path is relative to the exposed directory",
+	}, p.read); err != nil {
+		return err
+	}
+
+	if p.pollInterval > 0 {
+		go func() {
+			defer pkg.Recover()
+
+			p.watch()
+		}()
+	}
+	return nil
+}
+
+// Close stops watching for changes.
+func (p *Provider) Close() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+}
+
+// URI returns the resource uri of the file at path, a slash separated path relative to the root.
+func URI(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return uriPrefix + strings.Join(segments, "/")
+}
+
+func (p *Provider) watch() {
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.poll()
+		}
+	}
+}
+
+func (p *Provider) poll() {
+	files, err := p.scan()
+	if err != nil {
+		p.logger.Warnf("fs provider scan fail: %v", err)
+		return
+	}
+
+	p.mu.Lock()
+	old := p.files
+	p.files = files
+	s := p.server
+	p.mu.Unlock()
+
+	added, removed, changed := diffFiles(old, files)
+	// the files of a poll change the resource list at once
+	removedURIs := make([]string, len(removed))
+	for i, path := range removed {
+		removedURIs[i] = URI(path)
+	}
+	s.UpdateResources(p.resources(added, files), p.read, removedURIs)
+	for _, path := range append(changed, removed...) {
+		err = s.SendNotification4ResourcesUpdated(context.Background(), &protocol.ResourceUpdatedNotification{URI: URI(path)})
+		if err != nil && !errors.Is(err, pkg.ErrServerNotSupport) {
+			p.logger.Warnf("fs provider send resource updated fail: %v", err)
+		}
+	}
+}
+
+func diffFiles(old, current map[string]fileState) (added, removed, changed []string) {
+	for path, state := range current {
+		prev, ok := old[path]
+		switch {
+		case !ok:
+			added = append(added, path)
+		case !prev.modTime.Equal(state.modTime) || prev.size != state.size:
+			changed = append(changed, path)
+		}
+	}
+	for path := range old {
+		if _, ok := current[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
+
+// scan lists the regular files, symlinks are not followed
+func (p *Provider) scan() (map[string]fileState, error) {
+	files := make(map[string]fileState)
+	err := iofs.WalkDir(p.fsys, ".", func(path string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, iofs.ErrNotExist) {
+				// removed while walking
+				return nil
+			}
+			return err
+		}
+		files[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return files, err
+}
+
+func (p *Provider) resources(paths []string, files map[string]fileState) []*protocol.Resource {
+	resources := make([]*protocol.Resource, len(paths))
+	for i, path := range paths {
+		resources[i] = p.resource(path, files[path])
+	}
+	return resources
+}
+
+func (p *Provider) resource(path string, state fileState) *protocol.Resource {
+	return &protocol.Resource{
+		Name:     path,
+		URI:      URI(path),
+		MimeType: mime.TypeByExtension(filepath.Ext(path)),
+		Size:     state.size,
+	}
+}
+
+func (p *Provider) read(_ context.Context, request *protocol.ReadResourceRequest) (*protocol.ReadResourceResult, error) {
+	path, err := p.pathOf(request.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := iofs.Stat(p.fsys, path)
+	if err != nil {
+		if errors.Is(err, iofs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: uri=%s", pkg.ErrResourceNotFound, request.URI)
+		}
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%w: uri=%s is a directory", pkg.ErrResourceNotFound, request.URI)
+	}
+	version := fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size())
+	if request.GetIfNoneMatch() == version {
+		return protocol.NewNotModifiedReadResourceResult(version), nil
+	}
+	if p.maxFileSize > 0 && info.Size() > p.maxFileSize {
+		return nil, fmt.Errorf("file %s exceeds the maximum size of %d bytes", path, p.maxFileSize)
+	}
+
+	content, err := iofs.ReadFile(p.fsys, path)
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType := mime.TypeByExtension(filepath.Ext(path))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(content)
+	}
+	var result *protocol.ReadResourceResult
+	if isText(mimeType) && utf8.Valid(content) {
+		result = protocol.NewReadResourceResult([]protocol.ResourceContents{
+			&protocol.TextResourceContents{URI: request.URI, MimeType: mimeType, Text: string(content)},
+		})
+	} else {
+		result = protocol.NewReadResourceResult([]protocol.ResourceContents{
+			&protocol.BlobResourceContents{URI: request.URI, MimeType: mimeType, Blob: content},
+		})
+	}
+	result.SetVersion(version)
+	return result, nil
+}
+
+// pathOf maps a uri to a path of fsys, refusing anything that would leave the root
+func (p *Provider) pathOf(uri string) (string, error) {
+	if !strings.HasPrefix(uri, uriPrefix) {
+		return "", fmt.Errorf("%w: uri=%s", pkg.ErrResourceNotFound, uri)
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("invalid uri %s: %w", uri, err)
+	}
+
+	path := strings.TrimPrefix(u.Path, "/")
+	if !iofs.ValidPath(path) || path == "." {
+		return "", fmt.Errorf("invalid path %q in uri %s", path, uri)
+	}
+
+	if p.root != "" {
+		resolved, err := filepath.EvalSymlinks(filepath.Join(p.root, filepath.FromSlash(path)))
+		if err != nil {
+			if errors.Is(err, iofs.ErrNotExist) {
+				return "", fmt.Errorf("%w: uri=%s", pkg.ErrResourceNotFound, uri)
+			}
+			return "", err
+		}
+		rel, err := filepath.Rel(p.root, resolved)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("path %q in uri %s leaves the root directory", path, uri)
+		}
+	}
+	return path, nil
+}
+
+func isText(mimeType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/yaml", "application/x-yaml", "application/toml":
+		return true
+	default:
+		return false
+	}
+}
+
+func sortedPaths(files map[string]fileState) []string {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,181 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/client"
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"github.com/ThinkInAIXYZ/go-mcp/server"
+	"github.com/ThinkInAIXYZ/go-mcp/transport"
+)
+
+func TestProviderRead(t *testing.T) {
+	p := New(fstest.MapFS{
+		"readme.md":        {Data: []byte("# hello")},
+		"docs/a b.json":    {Data: []byte(`{"a":1}`)},
+		"images/logo.png":  {Data: []byte("\x89PNG\r\n\x1a\n\x00")},
+		"data/unknown.bin": {Data: []byte{0xff, 0xfe, 0x00}},
+	})
+
+	tests := []struct {
+		uri      string
+		wantText bool
+	}{
+		{uri: "file:///readme.md", wantText: true},
+		{uri: URI("docs/a b.json"), wantText: true},
+		{uri: "file:///images/logo.png", wantText: false},
+		{uri: "file:///data/unknown.bin", wantText: false},
+	}
+	for _, tt := range tests {
+		result, err := p.read(context.Background(), protocol.NewReadResourceRequest(tt.uri))
+		if err != nil {
+			t.Fatalf("read(%s) error = %v", tt.uri, err)
+		}
+		_, isText := result.Contents[0].(*protocol.TextResourceContents)
+		if isText != tt.wantText || result.Contents[0].GetMimeType() == "" {
+			t.Errorf("read(%s) = %T %s, want text %v", tt.uri, result.Contents[0], result.Contents[0].GetMimeType(), tt.wantText)
+		}
+	}
+
+	for _, uri := range []string{"file:///../secret", "file:///docs/../../secret", "file:///docs", "file:///missing.txt", "http://readme.md"} {
+		if _, err := p.read(context.Background(), protocol.NewReadResourceRequest(uri)); err == nil {
+			t.Errorf("read(%s) expected error", uri)
+		}
+	}
+	if _, err := p.read(context.Background(), protocol.NewReadResourceRequest("file:///missing.txt")); !errors.Is(err, pkg.ErrResourceNotFound) {
+		t.Errorf("read(missing) error = %v, want ErrResourceNotFound", err)
+	}
+}
+
+func TestProviderDirBoundary(t *testing.T) {
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "public.txt"), []byte("public"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(root, "link.txt")); err != nil {
+		t.Skipf("symlink: %v", err)
+	}
+
+	p, err := NewDir(root)
+	if err != nil {
+		t.Fatalf("NewDir() error = %v", err)
+	}
+	if _, err = p.read(context.Background(), protocol.NewReadResourceRequest("file:///public.txt")); err != nil {
+		t.Errorf("read(public.txt) error = %v", err)
+	}
+	if _, err = p.read(context.Background(), protocol.NewReadResourceRequest("file:///link.txt")); err == nil {
+		t.Errorf("read(link.txt) expected error for symlink leaving the root")
+	}
+
+	files, err := p.scan()
+	if err != nil {
+		t.Fatalf("scan() error = %v", err)
+	}
+	if !reflect.DeepEqual(sortedPaths(files), []string{"public.txt"}) {
+		t.Errorf("scan() = %v, want only public.txt", sortedPaths(files))
+	}
+}
+
+func TestDiffFiles(t *testing.T) {
+	now := time.Now()
+	old := map[string]fileState{
+		"same.txt":    {modTime: now, size: 1},
+		"changed.txt": {modTime: now, size: 1},
+		"removed.txt": {modTime: now, size: 1},
+	}
+	current := map[string]fileState{
+		"same.txt":    {modTime: now, size: 1},
+		"changed.txt": {modTime: now.Add(time.Second), size: 1},
+		"added.txt":   {modTime: now, size: 1},
+	}
+
+	added, removed, changed := diffFiles(old, current)
+	if !reflect.DeepEqual(added, []string{"added.txt"}) || !reflect.DeepEqual(removed, []string{"removed.txt"}) ||
+		!reflect.DeepEqual(changed, []string{"changed.txt"}) {
+		t.Errorf("diffFiles() = %v, %v, %v", added, removed, changed)
+	}
+}
+
+func TestProviderRegisterAndPoll(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	s, err := server.NewServer(transport.NewMockServerTransport(serverReader, serverWriter))
+	if err != nil {
+		t.Fatalf("NewServer: %+v", err)
+	}
+	p, err := NewDir(root, WithPollInterval(0))
+	if err != nil {
+		t.Fatalf("NewDir() error = %v", err)
+	}
+	if err = p.Register(s); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	defer p.Close()
+	go func() {
+		if err := s.Run(); err != nil {
+			t.Errorf("server.Run: %v", err)
+		}
+	}()
+	defer s.Shutdown(context.Background())
+
+	handler := &listChangedHandler{BaseNotifyHandler: client.NewBaseNotifyHandler(), ch: make(chan struct{}, 10)}
+	c, err := client.NewClient(transport.NewMockClientTransport(clientReader, clientWriter), client.WithNotifyHandler(handler))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer c.Close()
+
+	if err = os.Remove(filepath.Join(root, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"b.txt", "c.txt"} {
+		if err = os.WriteFile(filepath.Join(root, name), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.poll()
+
+	if paths := sortedPaths(p.files); !reflect.DeepEqual(paths, []string{"b.txt", "c.txt"}) {
+		t.Errorf("files = %v, want b.txt and c.txt", paths)
+	}
+	// one file removed and two added make a single list change
+	select {
+	case <-handler.ch:
+	case <-time.After(time.Second):
+		t.Fatalf("no resource list change notification")
+	}
+	select {
+	case <-handler.ch:
+		t.Errorf("more than one resource list change notification for a poll")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+type listChangedHandler struct {
+	*client.BaseNotifyHandler
+	ch chan struct{}
+}
+
+func (h *listChangedHandler) ResourceListChanged(context.Context, *protocol.ResourceListChangedNotification) error {
+	h.ch <- struct{}{}
+	return nil
+}
@@ -0,0 +1,296 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+)
+
+const (
+	defaultSamplingMaxTokens     = 1024
+	defaultSamplingMaxIterations = 5
+)
+
+// WithDefaultModelPreferences sets the model preferences of sampling requests made with Sample, SampleLoop
+// and SampleJSON that do not set their own, see WithSamplingModelPreferences.
+func WithDefaultModelPreferences(prefs *protocol.ModelPreferences) Option {
+	return func(s *Server) {
+		s.defaultModelPreferences = prefs
+	}
+}
+
+// Conversation builds the messages of a sampling request from Go values:
+// a string is sent as text, a protocol.Content as is, and any other value as its JSON encoding.
+type Conversation struct {
+	Messages []*protocol.SamplingMessage
+	err      error
+}
+
+func NewConversation() *Conversation {
+	return &Conversation{}
+}
+
+// User appends a message of the user.
+func (c *Conversation) User(v interface{}) *Conversation {
+	return c.Append(protocol.RoleUser, v)
+}
+
+// Assistant appends a message of the model, eg. an example answer.
+func (c *Conversation) Assistant(v interface{}) *Conversation {
+	return c.Append(protocol.RoleAssistant, v)
+}
+
+// Append appends a message, an encoding error is returned when the conversation is sampled.
+func (c *Conversation) Append(role protocol.Role, v interface{}) *Conversation {
+	content, err := toSamplingContent(v)
+	if err != nil {
+		if c.err == nil {
+			c.err = fmt.Errorf("conversation message %d: %w", len(c.Messages), err)
+		}
+		return c
+	}
+	c.Messages = append(c.Messages, &protocol.SamplingMessage{Role: role, Content: content})
+	return c
+}
+
+func toSamplingContent(v interface{}) (protocol.Content, error) {
+	switch v := v.(type) {
+	case protocol.Content:
+		return v, nil
+	case string:
+		return newTextContent(v), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return newTextContent(string(data)), nil
+	}
+}
+
+type SamplingOption func(*samplingOptions)
+
+type samplingOptions struct {
+	maxTokens        int
+	modelPreferences *protocol.ModelPreferences
+	requestOptions   []protocol.CreateMessageOption
+
+	retries      int
+	retryBackoff time.Duration
+	retryIf      func(error) bool
+
+	maxIterations int
+}
+
+// WithSamplingMaxTokens sets maxTokens of the requests, 1024 by default.
+func WithSamplingMaxTokens(maxTokens int) SamplingOption {
+	return func(o *samplingOptions) {
+		o.maxTokens = maxTokens
+	}
+}
+
+// WithSamplingModelPreferences overrides the server default, see WithDefaultModelPreferences.
+func WithSamplingModelPreferences(prefs *protocol.ModelPreferences) SamplingOption {
+	return func(o *samplingOptions) {
+		o.modelPreferences = prefs
+	}
+}
+
+// WithSamplingModelHints asks for the named models in order of preference, keeping the priorities
+// of the model preferences in effect.
+func WithSamplingModelHints(names ...string) SamplingOption {
+	return func(o *samplingOptions) {
+		prefs := &protocol.ModelPreferences{}
+		if o.modelPreferences != nil {
+			*prefs = *o.modelPreferences
+		}
+		prefs.Hints = make([]protocol.ModelHint, 0, len(names))
+		for _, name := range names {
+			prefs.Hints = append(prefs.Hints, protocol.ModelHint{Name: name})
+		}
+		o.modelPreferences = prefs
+	}
+}
+
+// WithSamplingRequestOptions applies options such as protocol.WithSystemPrompt or protocol.WithTemperature to the requests.
+func WithSamplingRequestOptions(opts ...protocol.CreateMessageOption) SamplingOption {
+	return func(o *samplingOptions) {
+		o.requestOptions = append(o.requestOptions, opts...)
+	}
+}
+
+// WithSamplingRetry retries a request failed with a transient error up to retries times,
+// waiting backoff before the first retry and doubling it after each one.
+func WithSamplingRetry(retries int, backoff time.Duration) SamplingOption {
+	return func(o *samplingOptions) {
+		o.retries = retries
+		o.retryBackoff = backoff
+	}
+}
+
+// WithSamplingRetryIf decides which errors are transient, by default IsTransientSamplingError.
+func WithSamplingRetryIf(retryIf func(error) bool) SamplingOption {
+	return func(o *samplingOptions) {
+		o.retryIf = retryIf
+	}
+}
+
+// WithSamplingMaxIterations limits how many requests SampleLoop and SampleJSON make, 5 by default.
+func WithSamplingMaxIterations(maxIterations int) SamplingOption {
+	return func(o *samplingOptions) {
+		o.maxIterations = maxIterations
+	}
+}
+
+// IsTransientSamplingError reports whether a failed sampling request may succeed when retried:
+// the client answered with an internal or connection error. A rejection by the client or the user is not transient.
+func IsTransientSamplingError(err error) bool {
+	var respErr *pkg.ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+	return respErr.Code == protocol.InternalError || respErr.Code == protocol.ConnectionError
+}
+
+func (server *Server) newSamplingOptions(opts []SamplingOption) *samplingOptions {
+	o := &samplingOptions{
+		maxTokens:        defaultSamplingMaxTokens,
+		modelPreferences: server.defaultModelPreferences,
+		retryIf:          IsTransientSamplingError,
+		maxIterations:    defaultSamplingMaxIterations,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Sample sends the conversation to the client of the session in ctx for sampling,
+// retrying transient errors as configured by WithSamplingRetry.
+func (server *Server) Sample(ctx context.Context, conversation *Conversation, opts ...SamplingOption) (*protocol.CreateMessageResult, error) {
+	return server.sample(ctx, conversation, server.newSamplingOptions(opts))
+}
+
+func (server *Server) sample(ctx context.Context, conversation *Conversation, o *samplingOptions) (*protocol.CreateMessageResult, error) {
+	if conversation.err != nil {
+		return nil, conversation.err
+	}
+	request := protocol.NewCreateMessageRequest(conversation.Messages, o.maxTokens, o.requestOptions...)
+	if request.ModelPreferences == nil {
+		request.ModelPreferences = o.modelPreferences
+	}
+
+	backoff := o.retryBackoff
+	for attempt := 0; ; attempt++ {
+		result, err := server.Sampling(ctx, request)
+		if err == nil || attempt >= o.retries || !o.retryIf(err) {
+			return result, err
+		}
+		server.logger.Warnf("sampling fail, retrying in %s: %v", backoff, err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+var errSamplingNoContent = errors.New("sampling result has no content")
+
+// isNilContent reports whether content is missing, a JSON null decodes into a nil *protocol.TextContent
+func isNilContent(content protocol.Content) bool {
+	if content == nil {
+		return true
+	}
+	v := reflect.ValueOf(content)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// SamplingStep handles the reply to the iteration-th request of SampleLoop (counted from 1).
+// It returns done to end the loop, or the next user message otherwise.
+type SamplingStep func(ctx context.Context, iteration int, result *protocol.CreateMessageResult) (next interface{}, done bool, err error)
+
+// SampleLoop samples the conversation until step is done, appending each reply and the next user
+// message step returns to conversation. It fails with pkg.ErrSamplingMaxIterations when step is not
+// done after the number of requests set by WithSamplingMaxIterations, and reports each iteration
+// as progress when the request being handled carries a progress token.
+func (server *Server) SampleLoop(ctx context.Context, conversation *Conversation, step SamplingStep, opts ...SamplingOption) (*protocol.CreateMessageResult, error) {
+	o := server.newSamplingOptions(opts)
+
+	for iteration := 1; iteration <= o.maxIterations; iteration++ {
+		server.sendSamplingProgress(ctx, iteration-1, o.maxIterations, fmt.Sprintf("sampling, iteration %d", iteration))
+
+		result, err := server.sample(ctx, conversation, o)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil || isNilContent(result.Content) {
+			return nil, errSamplingNoContent
+		}
+		conversation.Append(result.Role, result.Content)
+
+		next, done, err := step(ctx, iteration, result)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			server.sendSamplingProgress(ctx, o.maxIterations, o.maxIterations, "sampling done")
+			return result, nil
+		}
+		conversation.User(next)
+	}
+	return nil, fmt.Errorf("%w: %d", pkg.ErrSamplingMaxIterations, o.maxIterations)
+}
+
+func (server *Server) sendSamplingProgress(ctx context.Context, progress, total int, message string) {
+	if _, err := getProgressTokenFromCtx(ctx); err != nil {
+		return
+	}
+	if err := server.SendProgressNotification(ctx, protocol.NewProgressNotification(float64(progress), float64(total), message)); err != nil {
+		server.logger.Warnf("send sampling progress fail: %v", err)
+	}
+}
+
+// SampleJSON samples the conversation until the model replies with text holding the JSON encoding of a T,
+// optionally wrapped in a markdown code block. A reply that does not decode is answered with the error
+// so that the model can correct it, within the limit of WithSamplingMaxIterations.
+func SampleJSON[T any](ctx context.Context, server *Server, conversation *Conversation, opts ...SamplingOption) (T, error) {
+	var value T
+	_, err := server.SampleLoop(ctx, conversation, func(_ context.Context, _ int, result *protocol.CreateMessageResult) (interface{}, bool, error) {
+		text, ok := result.Content.(*protocol.TextContent)
+		if !ok {
+			return fmt.Sprintf("Reply with JSON text, not %s content.", result.Content.GetType()), false, nil
+		}
+		value = *new(T)
+		if err := pkg.JSONUnmarshal([]byte(trimCodeFence(text.Text)), &value); err != nil {
+			return fmt.Sprintf("The reply is not valid JSON for the requested value (%v), reply again with JSON only.", err), false, nil
+		}
+		return nil, true, nil
+	}, opts...)
+	return value, err
+}
+
+// trimCodeFence strips a markdown code block around text, models often add one around JSON
+func trimCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") || !strings.HasSuffix(text, "```") || len(text) < 6 {
+		return text
+	}
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "```"), "```")
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		// language tag such as ```json
+		text = text[i+1:]
+	}
+	return strings.TrimSpace(text)
+}
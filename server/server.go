@@ -68,6 +68,14 @@ func WithPagination(limit int) Option {
 	}
 }
 
+// WithResourceContentVersions hashes the contents of every resource read whose handler set no version,
+// so that clients can revalidate cached resources with conditional reads.
+func WithResourceContentVersions() Option {
+	return func(s *Server) {
+		s.resourceContentVersions = true
+	}
+}
+
 func WithGenSessionIDFunc(genSessionID func(context.Context) string) Option {
 	return func(s *Server) {
 		s.genSessionID = genSessionID
@@ -81,9 +89,17 @@ type Server struct {
 	prompts           pkg.SyncMap[*promptEntry]
 	resources         pkg.SyncMap[*resourceEntry]
 	resourceTemplates pkg.SyncMap[*resourceTemplateEntry]
+	// resourceTemplatesMu serializes template registration for the conflict check
+	resourceTemplatesMu sync.Mutex
 
 	sessionManager *session.Manager
 
+	subscriptionsMu sync.Mutex
+	subscriptions   map[string]*subscription
+
+	progressChanRW         sync.RWMutex
+	progressToken2listener map[string]*pkg.LatestSender[*protocol.ProgressNotification]
+
 	inShutdown   *pkg.AtomicBool // true when server is in shutdown
 	inFlyRequest sync.WaitGroup
 
@@ -93,6 +109,17 @@ type Server struct {
 
 	paginationLimit int
 
+	resourceChunkSize       int64
+	resourceContentVersions bool
+
+	// defaultModelPreferences apply to sampling requests made with Sample and SampleLoop
+	defaultModelPreferences *protocol.ModelPreferences
+
+	maxJSONDepth               int
+	maxBatchLength             int
+	maxResponseSize            int
+	oversizedToolResultHandler OversizedToolResultHandler
+
 	logger pkg.Logger
 
 	genSessionID func(ctx context.Context) string
@@ -106,10 +133,16 @@ func NewServer(t transport.ServerTransport, opts ...Option) (*Server, error) {
 			Resources: &protocol.ResourcesCapability{ListChanged: true, Subscribe: true},
 			Tools:     &protocol.ToolsCapability{ListChanged: true},
 		},
-		inShutdown:   pkg.NewAtomicBool(),
-		serverInfo:   &protocol.Implementation{},
-		logger:       pkg.DefaultLogger,
-		genSessionID: func(context.Context) string { return uuid.NewString() },
+		inShutdown:             pkg.NewAtomicBool(),
+		serverInfo:             &protocol.Implementation{},
+		logger:                 pkg.DefaultLogger,
+		genSessionID:           func(context.Context) string { return uuid.NewString() },
+		subscriptions:          make(map[string]*subscription),
+		progressToken2listener: make(map[string]*pkg.LatestSender[*protocol.ProgressNotification]),
+
+		resourceChunkSize: defaultResourceChunkSize,
+		maxJSONDepth:      defaultMaxJSONDepth,
+		maxBatchLength:    defaultMaxBatchLength,
 	}
 
 	t.SetReceiver(transport.ServerReceiverF(server.receive))
@@ -121,6 +154,7 @@ func NewServer(t transport.ServerTransport, opts ...Option) (*Server, error) {
 	}
 
 	server.sessionManager.SetLogger(server.logger)
+	server.sessionManager.SetCloseHook(server.releaseSessionSubscriptions)
 
 	t.SetSessionManager(server.sessionManager)
 
@@ -147,7 +181,19 @@ type toolEntry struct {
 
 type ToolHandlerFunc func(context.Context, *protocol.CallToolRequest) (*protocol.CallToolResult, error)
 
+// RegisterTool registers tool, which is dropped with an error logged when its input schema is invalid.
+// Use AddTool to handle the error.
 func (server *Server) RegisterTool(tool *protocol.Tool, toolHandler ToolHandlerFunc, middlewares ...ToolMiddleware) {
+	if err := server.AddTool(tool, toolHandler, middlewares...); err != nil {
+		server.logger.Errorf("register tool fail: %v", err)
+	}
+}
+
+// AddTool registers tool like RegisterTool, returning the error of an invalid input schema instead of logging it.
+func (server *Server) AddTool(tool *protocol.Tool, toolHandler ToolHandlerFunc, middlewares ...ToolMiddleware) error {
+	if err := tool.Validate(); err != nil {
+		return err
+	}
 	for i := len(middlewares) - 1; i >= 0; i-- {
 		toolHandler = middlewares[i](toolHandler)
 	}
@@ -155,9 +201,9 @@ func (server *Server) RegisterTool(tool *protocol.Tool, toolHandler ToolHandlerF
 	if !server.sessionManager.IsEmpty() {
 		if err := server.sendNotification4ToolListChanges(context.Background()); err != nil {
 			server.logger.Warnf("send notification toll list changes fail: %v", err)
-			return
 		}
 	}
+	return nil
 }
 
 func (server *Server) UnregisterTool(name string) {
@@ -200,12 +246,13 @@ func (server *Server) UnregisterPrompt(name string) {
 type resourceEntry struct {
 	resource *protocol.Resource
 	handler  ResourceHandlerFunc
+	hooks    *resourceHooks
 }
 
 type ResourceHandlerFunc func(context.Context, *protocol.ReadResourceRequest) (*protocol.ReadResourceResult, error)
 
-func (server *Server) RegisterResource(resource *protocol.Resource, resourceHandler ResourceHandlerFunc) {
-	server.resources.Store(resource.URI, &resourceEntry{resource: resource, handler: resourceHandler})
+func (server *Server) RegisterResource(resource *protocol.Resource, resourceHandler ResourceHandlerFunc, opts ...ResourceOption) {
+	server.resources.Store(resource.URI, &resourceEntry{resource: resource, handler: resourceHandler, hooks: newResourceHooks(opts)})
 	if !server.sessionManager.IsEmpty() {
 		if err := server.sendNotification4ResourceListChanges(context.Background()); err != nil {
 			server.logger.Warnf("send notification resource list changes fail: %v", err)
@@ -224,16 +271,57 @@ func (server *Server) UnregisterResource(uri string) {
 	}
 }
 
+// UpdateResources registers the resources of add, served by resourceHandler, and unregisters the uris of remove,
+// sending a single resource list change notification for the whole batch.
+func (server *Server) UpdateResources(add []*protocol.Resource, resourceHandler ResourceHandlerFunc, remove []string, opts ...ResourceOption) {
+	if len(add) == 0 && len(remove) == 0 {
+		return
+	}
+	for _, resource := range add {
+		server.resources.Store(resource.URI, &resourceEntry{resource: resource, handler: resourceHandler, hooks: newResourceHooks(opts)})
+	}
+	for _, uri := range remove {
+		server.resources.Delete(uri)
+	}
+	if !server.sessionManager.IsEmpty() {
+		if err := server.sendNotification4ResourceListChanges(context.Background()); err != nil {
+			server.logger.Warnf("send notification resource list changes fail: %v", err)
+			return
+		}
+	}
+}
+
 type resourceTemplateEntry struct {
 	resourceTemplate *protocol.ResourceTemplate
 	handler          ResourceHandlerFunc
+	hooks            *resourceHooks
+	specificity      templateSpecificity
+	shape            string
 }
 
-func (server *Server) RegisterResourceTemplate(resource *protocol.ResourceTemplate, resourceHandler ResourceHandlerFunc) error {
+// RegisterResourceTemplate serves the uris matching resource.URITemplate that are not registered as static resources.
+// When several templates match a uri the most specific one is used, eg. db://users/{id} over db://{table}/{id};
+// a template matching exactly the same uris as a registered one, such as db://{name} next to db://{table},
+// is rejected with pkg.ErrResourceTemplateConflict.
+func (server *Server) RegisterResourceTemplate(resource *protocol.ResourceTemplate, resourceHandler ResourceHandlerFunc, opts ...ResourceOption) error {
 	if err := resource.ParseURITemplate(); err != nil {
 		return err
 	}
-	server.resourceTemplates.Store(resource.URITemplate, &resourceTemplateEntry{resourceTemplate: resource, handler: resourceHandler})
+	entry := &resourceTemplateEntry{
+		resourceTemplate: resource,
+		handler:          resourceHandler,
+		hooks:            newResourceHooks(opts),
+	}
+	entry.specificity, entry.shape = parseTemplateSpecificity(resource.URITemplate)
+
+	server.resourceTemplatesMu.Lock()
+	if err := server.checkTemplateConflict(entry); err != nil {
+		server.resourceTemplatesMu.Unlock()
+		return err
+	}
+	server.resourceTemplates.Store(resource.URITemplate, entry)
+	server.resourceTemplatesMu.Unlock()
+
 	if !server.sessionManager.IsEmpty() {
 		if err := server.sendNotification4ResourceListChanges(context.Background()); err != nil {
 			server.logger.Warnf("send notification resource list changes fail: %v", err)
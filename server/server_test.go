@@ -2,13 +2,17 @@ package server
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 
@@ -928,3 +932,597 @@ func testServerRateLimiter(t *testing.T, tt testLimiter) {
 		// No error, continue
 	}
 }
+
+func TestRegisterFunc(t *testing.T) {
+	add, addHandler, err := newFuncTool("add", "add two numbers", func(a int, b *int) int {
+		if b == nil {
+			return a
+		}
+		return a + *b
+	}, []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("newFuncTool() error = %v", err)
+	}
+	if !reflect.DeepEqual(add.InputSchema.Required, []string{"a"}) || add.InputSchema.Properties["b"].Type != protocol.Integer {
+		t.Errorf("InputSchema = %+v", add.InputSchema)
+	}
+
+	result, err := addHandler(context.Background(), protocol.NewCallToolRequestWithRawArguments("add", json.RawMessage(`{"a":1,"b":2}`)))
+	if err != nil || result.Content[0].(*protocol.TextContent).Text != "3" {
+		t.Errorf("add() = %+v, %v", result, err)
+	}
+	if _, err = addHandler(context.Background(), protocol.NewCallToolRequest("add", map[string]interface{}{"b": 2})); err == nil {
+		t.Errorf("add() expected error for missing argument")
+	}
+
+	_, greetHandler, err := newFuncTool("greet", "", func(ctx context.Context, req currentTimeReq) (string, error) {
+		if req.Timezone == "" {
+			return "", fmt.Errorf("empty timezone")
+		}
+		return "hello from " + req.Timezone, nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("newFuncTool() error = %v", err)
+	}
+	result, err = greetHandler(context.Background(), protocol.NewCallToolRequest("greet", map[string]interface{}{"timezone": "UTC"}))
+	if err != nil || result.IsError || result.Content[0].(*protocol.TextContent).Text != "hello from UTC" {
+		t.Errorf("greet() = %+v, %v", result, err)
+	}
+	result, err = greetHandler(context.Background(), protocol.NewCallToolRequest("greet", map[string]interface{}{"timezone": ""}))
+	if err != nil || !result.IsError || result.Content[0].(*protocol.TextContent).Text != "empty timezone" {
+		t.Errorf("greet() = %+v, %v, want isError", result, err)
+	}
+
+	for _, fn := range []interface{}{
+		"not a function",
+		func(a, b int) int { return a + b },
+		func(a int) (int, string) { return a, "" },
+		func(names ...string) {},
+	} {
+		if _, _, err = newFuncTool("bad", "", fn, []string{"a"}); err == nil {
+			t.Errorf("newFuncTool(%T) expected error", fn)
+		}
+	}
+
+	server, err := NewServer(transport.NewMockServerTransport(io.NopCloser(&bytes.Buffer{}), io.Discard))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	// the tool fails validation at registration
+	if err = server.RegisterFunc("", "", func(a int) int { return a }, "a"); err == nil {
+		t.Errorf("RegisterFunc() of a tool without name expected error")
+	}
+}
+
+func TestAddTool(t *testing.T) {
+	server, err := NewServer(transport.NewMockServerTransport(io.NopCloser(&bytes.Buffer{}), io.Discard))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	handler := func(context.Context, *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+		return &protocol.CallToolResult{}, nil
+	}
+
+	broken := &protocol.Tool{Name: "broken", RawInputSchema: json.RawMessage(`{"type": "string"}`)}
+	if err = server.AddTool(broken, handler); err == nil {
+		t.Errorf("AddTool() of an invalid schema expected error")
+	}
+	if _, ok := server.tools.Load("broken"); ok {
+		t.Errorf("tool with an invalid schema registered")
+	}
+
+	valid := &protocol.Tool{Name: "valid", RawInputSchema: json.RawMessage(`{"type": "object"}`)}
+	if err = server.AddTool(valid, handler); err != nil {
+		t.Errorf("AddTool() error = %v", err)
+	}
+	if _, ok := server.tools.Load("valid"); !ok {
+		t.Errorf("tool not registered")
+	}
+}
+
+func TestCallToolWithRawSchema(t *testing.T) {
+	server, err := NewServer(transport.NewMockServerTransport(io.NopCloser(&bytes.Buffer{}), io.Discard))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	tool, err := protocol.NewToolWithRawSchema("resize", "", json.RawMessage(
+		`{"type": "object", "properties": {"width": {"type": "integer", "minimum": 1}}, "required": ["width"]}`))
+	if err != nil {
+		t.Fatalf("NewToolWithRawSchema() error = %v", err)
+	}
+	called := false
+	if err = server.AddTool(tool, func(context.Context, *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+		called = true
+		return &protocol.CallToolResult{}, nil
+	}); err != nil {
+		t.Fatalf("AddTool() error = %v", err)
+	}
+
+	call := func(arguments string) *protocol.JSONRPCResponse {
+		return server.receiveRequest(context.Background(), "", &protocol.JSONRPCRequest{
+			JSONRPC:   "2.0",
+			ID:        1,
+			Method:    protocol.ToolsCall,
+			RawParams: json.RawMessage(`{"name": "resize", "arguments": ` + arguments + `}`),
+		})
+	}
+
+	response := call(`{"width": 0}`)
+	if response.Error == nil || response.Error.Code != protocol.InvalidParams {
+		t.Fatalf("call with invalid arguments = %+v, want InvalidParams", response)
+	}
+	errs, ok := response.Error.Data.(protocol.ValidationErrors)
+	if !ok || len(errs) != 1 || errs[0].Path != "/width" {
+		t.Errorf("error data = %#v, want the violation at /width", response.Error.Data)
+	}
+	if called {
+		t.Errorf("handler called with invalid arguments")
+	}
+
+	if response = call(`{"width": 3}`); response.Error != nil || !called {
+		t.Errorf("call with valid arguments = %+v, called = %v", response.Error, called)
+	}
+}
+
+func TestServerSubscribeResourceTemplate(t *testing.T) {
+	server, err := NewServer(transport.NewMockServerTransport(io.NopCloser(&bytes.Buffer{}), io.Discard))
+	if err != nil {
+		t.Fatalf("NewServer: %+v", err)
+	}
+
+	var watching []string
+	handler := func(context.Context, *protocol.ReadResourceRequest) (*protocol.ReadResourceResult, error) {
+		return &protocol.ReadResourceResult{}, nil
+	}
+	if err = server.RegisterResourceTemplate(&protocol.ResourceTemplate{URITemplate: "logs:///{name}", Name: "logs"}, handler,
+		WithSubscribeHook(func(_ context.Context, uri string) error {
+			if uri == "logs:///forbidden" {
+				return fmt.Errorf("forbidden")
+			}
+			watching = append(watching, uri)
+			return nil
+		}),
+		WithUnsubscribeHook(func(_ context.Context, uri string) {
+			for i, w := range watching {
+				if w == uri {
+					watching = append(watching[:i], watching[i+1:]...)
+					break
+				}
+			}
+		}),
+	); err != nil {
+		t.Fatalf("RegisterResourceTemplate: %+v", err)
+	}
+	server.RegisterResource(&protocol.Resource{URI: "config:///app", Name: "config"}, handler)
+
+	subscribe := func(sessionID, uri string) error {
+		params, _ := json.Marshal(protocol.SubscribeRequest{URI: uri})
+		_, err := server.handleRequestWithSubscribeResourceChange(context.Background(), sessionID, params)
+		return err
+	}
+	unsubscribe := func(sessionID, uri string) {
+		params, _ := json.Marshal(protocol.UnsubscribeRequest{URI: uri})
+		if _, err := server.handleRequestWithUnSubscribeResourceChange(sessionID, params); err != nil {
+			t.Fatalf("unsubscribe %s: %+v", uri, err)
+		}
+	}
+
+	session1 := server.sessionManager.CreateSession(context.Background())
+	session2 := server.sessionManager.CreateSession(context.Background())
+
+	for _, uri := range []string{"logs:///app", "logs:///{name}", "config:///*"} {
+		if err = subscribe(session1, uri); err != nil {
+			t.Fatalf("subscribe %s: %+v", uri, err)
+		}
+	}
+	if err = subscribe(session2, "logs:///app"); err != nil {
+		t.Fatalf("subscribe: %+v", err)
+	}
+	if err = subscribe(session1, "unknown:///x"); !errors.Is(err, pkg.ErrResourceNotFound) {
+		t.Errorf("subscribe unknown uri error = %v, want ErrResourceNotFound", err)
+	}
+	if err = subscribe(session1, "logs:///forbidden"); err == nil {
+		t.Errorf("subscribe expected hook error")
+	}
+	if !reflect.DeepEqual(watching, []string{"logs:///app", "logs:///{name}"}) {
+		t.Errorf("watching = %v", watching)
+	}
+
+	state1, _ := server.sessionManager.GetSession(session1)
+	state2, _ := server.sessionManager.GetSession(session2)
+	for uri, want := range map[string][2]bool{
+		"logs:///app":   {true, true},
+		"logs:///other": {true, false},
+		"config:///app": {true, false},
+		"other:///app":  {false, false},
+	} {
+		if got := [2]bool{server.isSubscribed(state1, uri), server.isSubscribed(state2, uri)}; got != want {
+			t.Errorf("isSubscribed(%s) = %v, want %v", uri, got, want)
+		}
+	}
+
+	unsubscribe(session1, "logs:///app")
+	if !reflect.DeepEqual(watching, []string{"logs:///app", "logs:///{name}"}) {
+		t.Errorf("watching = %v, want logs:///app kept for session2", watching)
+	}
+	server.sessionManager.CloseSession(session2)
+	unsubscribe(session1, "logs:///{name}")
+	if len(watching) != 0 {
+		t.Errorf("watching = %v, want none", watching)
+	}
+}
+
+func TestResourceTemplatePrecedence(t *testing.T) {
+	server, err := NewServer(transport.NewMockServerTransport(io.NopCloser(&bytes.Buffer{}), io.Discard))
+	if err != nil {
+		t.Fatalf("NewServer: %+v", err)
+	}
+
+	var (
+		served    string
+		arguments map[string]interface{}
+	)
+	handlerOf := func(name string) ResourceHandlerFunc {
+		return func(_ context.Context, request *protocol.ReadResourceRequest) (*protocol.ReadResourceResult, error) {
+			served, arguments = name, request.Arguments
+			return &protocol.ReadResourceResult{}, nil
+		}
+	}
+	templates := []*protocol.ResourceTemplate{
+		{URITemplate: "db://{table}", Name: "table"},
+		{URITemplate: "db://users", Name: "users"},
+		{URITemplate: "db://{table}/{id}", Name: "row"},
+		{
+			URITemplate:   "db://users/{id}",
+			Name:          "user",
+			VariableTypes: map[string]protocol.TemplateVariableType{"id": protocol.TemplateVariableInt},
+		},
+		{URITemplate: "files://{+path}", Name: "file"},
+		{URITemplate: "files://docs{/segments*}", Name: "doc"},
+		{
+			URITemplate:   "tags://{tags}",
+			Name:          "tags",
+			VariableTypes: map[string]protocol.TemplateVariableType{"tags": protocol.TemplateVariableList},
+		},
+	}
+	// registered in both orders, the result must not depend on it
+	for i := len(templates) - 1; i >= 0; i-- {
+		if err = server.RegisterResourceTemplate(templates[i], handlerOf(templates[i].Name)); err != nil {
+			t.Fatalf("RegisterResourceTemplate(%s): %+v", templates[i].URITemplate, err)
+		}
+	}
+	server.RegisterResource(&protocol.Resource{URI: "db://orders", Name: "orders"}, handlerOf("orders"))
+
+	tests := []struct {
+		uri           string
+		wantServed    string
+		wantArguments map[string]interface{}
+	}{
+		{uri: "db://orders", wantServed: "orders"},
+		{uri: "db://users", wantServed: "users", wantArguments: map[string]interface{}{}},
+		{uri: "db://accounts", wantServed: "table", wantArguments: map[string]interface{}{"table": "accounts"}},
+		{uri: "db://users/42", wantServed: "user", wantArguments: map[string]interface{}{"id": int64(42)}},
+		{uri: "db://users/bob", wantServed: "row", wantArguments: map[string]interface{}{"table": "users", "id": "bob"}},
+		{uri: "files://docs/a/b", wantServed: "doc", wantArguments: map[string]interface{}{"segments": []string{"a", "b"}}},
+		{uri: "files://docs/a", wantServed: "doc", wantArguments: map[string]interface{}{"segments": []string{"a"}}},
+		{uri: "files://src/main.go", wantServed: "file", wantArguments: map[string]interface{}{"path": "src/main.go"}},
+		{uri: "tags://a,b", wantServed: "tags", wantArguments: map[string]interface{}{"tags": []string{"a", "b"}}},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 10; i++ {
+			served, arguments = "", nil
+			params, _ := json.Marshal(protocol.NewReadResourceRequest(tt.uri))
+			if _, err = server.handleRequestWithReadResource(context.Background(), params); err != nil {
+				t.Fatalf("read %s: %+v", tt.uri, err)
+			}
+			if served != tt.wantServed || !reflect.DeepEqual(arguments, tt.wantArguments) {
+				t.Fatalf("read %s served by %s with %#v, want %s with %#v", tt.uri, served, arguments, tt.wantServed, tt.wantArguments)
+			}
+		}
+	}
+
+	if err = server.RegisterResourceTemplate(&protocol.ResourceTemplate{URITemplate: "db://{name}", Name: "name"}, handlerOf("name")); !errors.Is(err, pkg.ErrResourceTemplateConflict) {
+		t.Errorf("RegisterResourceTemplate(db://{name}) error = %v, want ErrResourceTemplateConflict", err)
+	}
+	if err = server.RegisterResourceTemplate(&protocol.ResourceTemplate{URITemplate: "db://{table}", Name: "table"}, handlerOf("table")); err != nil {
+		t.Errorf("re-registering db://{table}: %+v", err)
+	}
+	err = server.RegisterResourceTemplate(&protocol.ResourceTemplate{
+		URITemplate:   "logs://{day}",
+		VariableTypes: map[string]protocol.TemplateVariableType{"month": protocol.TemplateVariableInt},
+	}, handlerOf("logs"))
+	if err == nil {
+		t.Errorf("RegisterResourceTemplate with a type of an unknown variable expected error")
+	}
+}
+
+func TestProgressReporter(t *testing.T) {
+	server, err := NewServer(transport.NewMockServerTransport(io.NopCloser(&bytes.Buffer{}), io.Discard))
+	if err != nil {
+		t.Fatalf("NewServer: %+v", err)
+	}
+
+	ch := make(chan []byte, 100)
+	ctx := setSendChanToCtx(setProgressTokenToCtx(context.Background(), "token"), ch)
+	reporter := server.NewProgressReporter(ctx, WithProgressTotal(1000), WithProgressInterval(time.Hour))
+	for i := 0; i < 1000; i++ {
+		reporter.Advance(1)
+	}
+	reporter.SetMessage("done")
+	reporter.Done()
+	close(ch)
+
+	var notifications []*protocol.ProgressNotification
+	for message := range ch {
+		var notify struct {
+			Params *protocol.ProgressNotification `json:"params"`
+		}
+		if err = json.Unmarshal(message, &notify); err != nil {
+			t.Fatalf("unmarshal %s: %v", message, err)
+		}
+		notifications = append(notifications, notify.Params)
+	}
+	// the first update is sent right away, the rest is coalesced until Done
+	if len(notifications) != 2 {
+		t.Fatalf("notifications = %d, want 2", len(notifications))
+	}
+	last := notifications[1]
+	if last.Progress != 1000 || last.Total != 1000 || last.Message != "done" || last.ProgressToken != "token" {
+		t.Errorf("last notification = %+v", last)
+	}
+
+	// the held back update is sent at the end of the interval, without waiting for Done
+	ch = make(chan []byte, 100)
+	ctx = setSendChanToCtx(setProgressTokenToCtx(context.Background(), "token"), ch)
+	reporter = server.NewProgressReporter(ctx, WithProgressInterval(50*time.Millisecond))
+	reporter.Advance(1)
+	reporter.Advance(1)
+	<-ch
+	select {
+	case message := <-ch:
+		var notify struct {
+			Params *protocol.ProgressNotification `json:"params"`
+		}
+		if err = json.Unmarshal(message, &notify); err != nil {
+			t.Fatalf("unmarshal %s: %v", message, err)
+		}
+		if notify.Params.Progress != 2 {
+			t.Errorf("flushed notification = %+v, want progress 2", notify.Params)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("held back update not sent at the end of the interval")
+	}
+	reporter.Done()
+	if len(ch) != 0 {
+		t.Errorf("Done sent %d notifications, want none left", len(ch))
+	}
+
+	// without a progress token the reporter does nothing
+	reporter = server.NewProgressReporter(setSendChanToCtx(context.Background(), nil))
+	reporter.Advance(1)
+	reporter.Done()
+}
+
+func TestProgressListener(t *testing.T) {
+	server, err := NewServer(transport.NewMockServerTransport(io.NopCloser(&bytes.Buffer{}), io.Discard))
+	if err != nil {
+		t.Fatalf("NewServer: %+v", err)
+	}
+
+	ch := make(chan *protocol.ProgressNotification, 1)
+	listener := pkg.NewLatestSender(ch)
+	server.progressToken2listener["token"] = listener
+
+	// nobody reads ch yet, the notifications beyond its buffer are coalesced without blocking
+	start := time.Now()
+	for i := 1; i <= 3; i++ {
+		params := fmt.Sprintf(`{"progressToken":"token","progress":%d}`, i)
+		if err = server.handleNotifyWithProgress(json.RawMessage(params)); err != nil {
+			t.Fatalf("handleNotifyWithProgress: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("handleNotifyWithProgress took %v, want no blocking", elapsed)
+	}
+
+	if notify := <-ch; notify.Progress != 1 {
+		t.Errorf("first notification = %+v", notify)
+	}
+	delete(server.progressToken2listener, "token")
+	listener.Close()
+
+	var progress []float64
+	for notify := range ch {
+		progress = append(progress, notify.Progress)
+	}
+	if !reflect.DeepEqual(progress, []float64{3}) {
+		t.Errorf("notifications after the first = %v, want the latest only", progress)
+	}
+}
+
+func TestStreamResourceHandler(t *testing.T) {
+	server, err := NewServer(transport.NewMockServerTransport(io.NopCloser(&bytes.Buffer{}), io.Discard), WithResourceChunkSize(10))
+	if err != nil {
+		t.Fatalf("NewServer: %+v", err)
+	}
+
+	text := strings.Repeat("héllo wörld ", 5)
+	tests := []struct {
+		name   string
+		stream func() *ResourceStream
+		want   string
+	}{
+		{
+			name: "text_unknown_size",
+			stream: func() *ResourceStream {
+				// hide io.Seeker and io.ReaderAt so the offset is reached by reading
+				return &ResourceStream{Reader: io.MultiReader(strings.NewReader(text)), Size: -1, Text: true}
+			},
+			want: text,
+		},
+		{
+			name: "blob_known_size",
+			stream: func() *ResourceStream {
+				return &ResourceStream{Reader: bytes.NewReader([]byte(text)), Size: int64(len(text))}
+			},
+			want: text,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := server.StreamResourceHandler(func(context.Context, *protocol.ReadResourceRequest) (*ResourceStream, error) {
+				return tt.stream(), nil
+			})
+
+			var (
+				got    []byte
+				offset int64
+			)
+			for i := 0; ; i++ {
+				if i > len(tt.want) {
+					t.Fatalf("no end after %d reads", i)
+				}
+				request := &protocol.ReadResourceRequest{
+					URI:  "test:///stream",
+					Meta: map[string]interface{}{protocol.ResourceRangeKey: &protocol.ResourceRange{Offset: offset}},
+				}
+				result, err := handler(context.Background(), request)
+				if err != nil {
+					t.Fatalf("handler: %+v", err)
+				}
+				switch c := result.Contents[0].(type) {
+				case *protocol.TextResourceContents:
+					if !utf8.ValidString(c.Text) {
+						t.Fatalf("range %d cuts a character: %q", i, c.Text)
+					}
+					got = append(got, c.Text...)
+				case *protocol.BlobResourceContents:
+					got = append(got, c.Blob...)
+				}
+
+				returned, ok := protocol.GetResourceRange(result.Meta)
+				if !ok || returned.Length > 10 {
+					t.Fatalf("range = %+v", returned)
+				}
+				if returned.EOF {
+					if returned.Total != int64(len(tt.want)) {
+						t.Errorf("total = %d, want %d", returned.Total, len(tt.want))
+					}
+					break
+				}
+				offset = returned.Offset + returned.Length
+			}
+			if string(got) != tt.want {
+				t.Errorf("content = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// a handler returning neither a stream nor an error fails the read instead of the server
+	server.RegisterResourceStream(&protocol.Resource{URI: "test:///nil", Name: "nil"},
+		func(context.Context, *protocol.ReadResourceRequest) (*ResourceStream, error) {
+			return nil, nil
+		})
+	response := server.receiveRequest(context.Background(), "", &protocol.JSONRPCRequest{
+		JSONRPC:   "2.0",
+		ID:        1,
+		Method:    protocol.ResourcesRead,
+		RawParams: json.RawMessage(`{"uri": "test:///nil"}`),
+	})
+	if response.Error == nil || response.Error.Code != protocol.InternalError {
+		t.Errorf("response = %+v, want an internal error", response)
+	}
+}
+
+func TestServerMessageLimits(t *testing.T) {
+	newServer := func(opts ...Option) *Server {
+		server, err := NewServer(transport.NewMockServerTransport(io.NopCloser(&bytes.Buffer{}), io.Discard), opts...)
+		if err != nil {
+			t.Fatalf("NewServer: %+v", err)
+		}
+		testTool, err := protocol.NewTool("big_tool", "big_tool", currentTimeReq{})
+		if err != nil {
+			t.Fatalf("NewTool: %+v", err)
+		}
+		server.RegisterTool(testTool, func(context.Context, *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+			return &protocol.CallToolResult{Content: []protocol.Content{
+				&protocol.TextContent{Type: "text", Text: strings.Repeat("日本語\n", 1000)},
+				&protocol.ImageContent{Type: "image", Data: bytes.Repeat([]byte("A"), 1000), MimeType: "image/png"},
+			}}, nil
+		})
+		return server
+	}
+	receive := func(server *Server, msg string) (*protocol.JSONRPCResponse, []byte, error) {
+		ch, err := server.receive(context.Background(), "", []byte(msg))
+		if err != nil {
+			return nil, nil, err
+		}
+		message := <-ch
+		resp := &protocol.JSONRPCResponse{}
+		if err = json.Unmarshal(message, resp); err != nil {
+			t.Fatalf("unmarshal %s: %v", message, err)
+		}
+		return resp, message, nil
+	}
+	callTool := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"big_tool"}}`
+
+	t.Run("depth", func(t *testing.T) {
+		server := newServer(WithMaxJSONDepth(4))
+		resp, _, err := receive(server, `{"jsonrpc":"2.0","id":1,"method":"ping","params":{"a":{"b":[{"c":1}]}}}`)
+		if err != nil {
+			t.Fatalf("receive: %+v", err)
+		}
+		if resp.Error == nil || resp.Error.Code != protocol.InvalidRequest {
+			t.Errorf("response = %+v, want an invalid request error", resp)
+		}
+
+		_, err = server.receive(context.Background(), "", []byte(`{"jsonrpc":"2.0","method":"x","params":[[[[1]]]]}`))
+		if !errors.Is(err, pkg.ErrJSONLimitExceeded) {
+			t.Errorf("notification error = %v, want %v", err, pkg.ErrJSONLimitExceeded)
+		}
+
+		// brackets in strings do not count
+		if _, _, err = receive(server, `{"jsonrpc":"2.0","id":1,"method":"ping","params":{"a":"[[[[{{{{\"]]"}}`); err != nil {
+			t.Errorf("receive: %+v", err)
+		}
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		server := newServer(WithMaxBatchLength(2))
+		_, err := server.receive(context.Background(), "", []byte(`[{"id":1},{"id":2},{"id":3}]`))
+		if !errors.Is(err, pkg.ErrJSONLimitExceeded) {
+			t.Errorf("error = %v, want %v", err, pkg.ErrJSONLimitExceeded)
+		}
+	})
+
+	t.Run("response_size", func(t *testing.T) {
+		resp, message, err := receive(newServer(WithMaxResponseSize(1000)), callTool)
+		if err != nil {
+			t.Fatalf("receive: %+v", err)
+		}
+		if resp.Error == nil || resp.Error.Code != protocol.InternalError || len(message) > 1000 {
+			t.Errorf("response = %s, want an internal error", message)
+		}
+	})
+
+	t.Run("truncate_tool_result", func(t *testing.T) {
+		resp, message, err := receive(newServer(WithMaxResponseSize(1000), WithOversizedToolResultHandler(TruncateToolResult)), callTool)
+		if err != nil {
+			t.Fatalf("receive: %+v", err)
+		}
+		if resp.Error != nil || len(message) > 1000 {
+			t.Fatalf("response = %s", message)
+		}
+		result := &protocol.CallToolResult{}
+		if err = json.Unmarshal(resp.RawResult, result); err != nil {
+			t.Fatalf("unmarshal result: %v", err)
+		}
+		if len(result.Content) != 2 {
+			t.Fatalf("contents = %d, want the cut text and the note", len(result.Content))
+		}
+		text := result.Content[0].(*protocol.TextContent).Text
+		if text == "" || !utf8.ValidString(text) || !strings.HasPrefix(strings.Repeat("日本語\n", 1000), text) {
+			t.Errorf("cut text = %q", text)
+		}
+	})
+}
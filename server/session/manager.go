@@ -19,6 +19,8 @@ type Manager struct {
 
 	detection   func(ctx context.Context, sessionID string) error
 	maxIdleTime time.Duration
+
+	onClose func(sessionID string, state *State)
 }
 
 func NewManager(detection func(ctx context.Context, sessionID string) error, genSessionID func(ctx context.Context) string) *Manager {
@@ -38,6 +40,11 @@ func (m *Manager) SetLogger(logger pkg.Logger) {
 	m.logger = logger
 }
 
+// SetCloseHook registers a function called once for every session after it is closed.
+func (m *Manager) SetCloseHook(hook func(sessionID string, state *State)) {
+	m.onClose = hook
+}
+
 func (m *Manager) CreateSession(ctx context.Context) string {
 	sessionID := m.genSessionID(ctx)
 	state := NewState()
@@ -106,6 +113,10 @@ func (m *Manager) CloseSession(sessionID string) {
 	}
 	state.Close()
 	m.closedSessions.Store(sessionID, struct{}{})
+
+	if m.onClose != nil {
+		m.onClose(sessionID, state)
+	}
 }
 
 func (m *Manager) CloseAllSessions() {
@@ -92,6 +92,10 @@ func (s *State) GetSubscribedResources() cmap.ConcurrentMap[string, struct{}] {
 	return s.subscribedResources
 }
 
+func (s *State) IsClosed() bool {
+	return s.closed.Load()
+}
+
 func (s *State) Close() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ThinkInAIXYZ/go-mcp/server/session"
+)
+
+type ResourceOption func(*resourceHooks)
+
+type resourceHooks struct {
+	onSubscribe   func(ctx context.Context, uri string) error
+	onUnsubscribe func(ctx context.Context, uri string)
+}
+
+func newResourceHooks(opts []ResourceOption) *resourceHooks {
+	hooks := &resourceHooks{}
+	for _, opt := range opts {
+		opt(hooks)
+	}
+	return hooks
+}
+
+// WithSubscribeHook is called when the first session subscribes to uri, eg. to start watching
+// the underlying data. An error rejects the subscription.
+// For a resource template, uri is the subscribed uri: either one that matches the template or the template itself.
+func WithSubscribeHook(hook func(ctx context.Context, uri string) error) ResourceOption {
+	return func(h *resourceHooks) {
+		h.onSubscribe = hook
+	}
+}
+
+// WithUnsubscribeHook is called when the last session subscribed to uri unsubscribes or is closed.
+func WithUnsubscribeHook(hook func(ctx context.Context, uri string)) ResourceOption {
+	return func(h *resourceHooks) {
+		h.onUnsubscribe = hook
+	}
+}
+
+// subscription counts the sessions subscribed to one uri
+type subscription struct {
+	sessions int
+	hooks    *resourceHooks
+}
+
+// lookupSubscription checks that uri can be subscribed to and returns the hooks of the resource it refers to.
+// Besides the uri of a static resource or one matching a resource template, a session may subscribe to a
+// registered uri template itself, or to a prefix ending with "*", to receive updates for every matching uri.
+// Prefix subscriptions have no hooks.
+func (server *Server) lookupSubscription(uri string) (*resourceHooks, bool) {
+	if entry, ok := server.resources.Load(uri); ok {
+		return entry.hooks, true
+	}
+	if entry, ok := server.resourceTemplates.Load(uri); ok {
+		return entry.hooks, true
+	}
+
+	if strings.HasSuffix(uri, "*") {
+		prefix := strings.TrimSuffix(uri, "*")
+		found := false
+		server.resources.Range(func(resourceURI string, _ *resourceEntry) bool {
+			found = strings.HasPrefix(resourceURI, prefix)
+			return !found
+		})
+		server.resourceTemplates.Range(func(uriTemplate string, _ *resourceTemplateEntry) bool {
+			literal := uriTemplate
+			if i := strings.IndexByte(uriTemplate, '{'); i >= 0 {
+				literal = uriTemplate[:i]
+			}
+			found = found || strings.HasPrefix(literal, prefix) || strings.HasPrefix(prefix, literal)
+			return !found
+		})
+		return &resourceHooks{}, found
+	}
+
+	entry, _ := server.matchResourceTemplate(uri)
+	if entry == nil {
+		return nil, false
+	}
+	return entry.hooks, true
+}
+
+// matchesSubscription reports whether an update of uri concerns the subscribed uri
+func (server *Server) matchesSubscription(subscribed, uri string) bool {
+	if subscribed == uri {
+		return true
+	}
+	if strings.HasSuffix(subscribed, "*") {
+		return strings.HasPrefix(uri, strings.TrimSuffix(subscribed, "*"))
+	}
+	if entry, ok := server.resourceTemplates.Load(subscribed); ok {
+		return matchesTemplate(uri, entry.resourceTemplate.URITemplateParsed)
+	}
+	return false
+}
+
+func (server *Server) isSubscribed(s *session.State, uri string) bool {
+	subscribed := s.GetSubscribedResources()
+	if subscribed.Has(uri) {
+		return true
+	}
+	for _, key := range subscribed.Keys() {
+		if server.matchesSubscription(key, uri) {
+			return true
+		}
+	}
+	return false
+}
+
+// acquireSubscription counts one more session subscribed to uri, calling the subscribe hook for the first one.
+// Hooks run under the lock so that they never overlap for the same uri, they should return quickly.
+func (server *Server) acquireSubscription(ctx context.Context, uri string, hooks *resourceHooks) error {
+	server.subscriptionsMu.Lock()
+	defer server.subscriptionsMu.Unlock()
+
+	sub, ok := server.subscriptions[uri]
+	if !ok {
+		if hooks.onSubscribe != nil {
+			if err := hooks.onSubscribe(ctx, uri); err != nil {
+				return err
+			}
+		}
+		sub = &subscription{hooks: hooks}
+		server.subscriptions[uri] = sub
+	}
+	sub.sessions++
+	return nil
+}
+
+// releaseSubscription counts one session less subscribed to uri, calling the unsubscribe hook for the last one.
+func (server *Server) releaseSubscription(ctx context.Context, uri string) {
+	server.subscriptionsMu.Lock()
+	defer server.subscriptionsMu.Unlock()
+
+	sub, ok := server.subscriptions[uri]
+	if !ok {
+		return
+	}
+	if sub.sessions--; sub.sessions > 0 {
+		return
+	}
+	delete(server.subscriptions, uri)
+	if sub.hooks.onUnsubscribe != nil {
+		sub.hooks.onUnsubscribe(ctx, uri)
+	}
+}
+
+func (server *Server) releaseSessionSubscriptions(_ string, s *session.State) {
+	subscribed := s.GetSubscribedResources()
+	for _, uri := range subscribed.Keys() {
+		if _, ok := subscribed.Pop(uri); ok {
+			server.releaseSubscription(context.Background(), uri)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// A server process for the supervision tests of the stdio client transport.
+// echo mode writes back every line and exits with status 3 on a line containing "crash",
+// stubborn mode ignores SIGTERM and the end of its stdin,
+// orphan mode leaves a child holding its stdout and stderr when it exits at the end of its stdin.
+func main() {
+	mode := flag.String("mode", "echo", "echo, stubborn or orphan")
+	flag.Parse()
+
+	if *mode == "orphan" {
+		child := exec.Command("sleep", "10")
+		child.Stdout, child.Stderr = os.Stdout, os.Stderr
+		if err := child.Start(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+		return
+	}
+
+	if *mode == "stubborn" {
+		signal.Ignore(syscall.SIGTERM)
+		fmt.Fprintln(os.Stderr, "ignoring SIGTERM")
+		time.Sleep(time.Hour)
+		return
+	}
+
+	s := bufio.NewScanner(os.Stdin)
+	for s.Scan() {
+		line := s.Text()
+		if strings.Contains(line, "crash") {
+			fmt.Fprintln(os.Stderr, "panic: boom")
+			os.Exit(3)
+		}
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/client"
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"github.com/ThinkInAIXYZ/go-mcp/server"
+	"github.com/ThinkInAIXYZ/go-mcp/transport"
+)
+
+func TestRequestProgress(t *testing.T) {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	mcpServer, err := server.NewServer(transport.NewMockServerTransport(serverReader, serverWriter))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	const steps = 200
+	report := func(ctx context.Context) {
+		reporter := mcpServer.NewProgressReporter(ctx, server.WithProgressTotal(steps), server.WithProgressInterval(0))
+		for i := 0; i < steps; i++ {
+			reporter.Advance(1)
+		}
+		reporter.Done()
+	}
+	resource := &protocol.Resource{URI: "data:///large", Name: "large", MimeType: "text/plain"}
+	mcpServer.RegisterResource(resource, func(ctx context.Context, request *protocol.ReadResourceRequest) (*protocol.ReadResourceResult, error) {
+		report(ctx)
+		return protocol.NewReadResourceResult([]protocol.ResourceContents{
+			&protocol.TextResourceContents{URI: request.URI, MimeType: "text/plain", Text: "large"},
+		}), nil
+	})
+	mcpServer.RegisterPrompt(&protocol.Prompt{Name: "slow"}, func(ctx context.Context, _ *protocol.GetPromptRequest) (*protocol.GetPromptResult, error) {
+		report(ctx)
+		return &protocol.GetPromptResult{Messages: []*protocol.PromptMessage{
+			{Role: protocol.RoleUser, Content: &protocol.TextContent{Type: "text", Text: "hi"}},
+		}}, nil
+	})
+	go func() {
+		if err := mcpServer.Run(); err != nil {
+			t.Errorf("server.Run: %v", err)
+		}
+	}()
+	defer mcpServer.Shutdown(context.Background())
+
+	mcpClient, err := client.NewClient(transport.NewMockClientTransport(clientReader, clientWriter))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer mcpClient.Close()
+
+	var (
+		mu       sync.Mutex
+		received []float64
+		returned bool
+	)
+	slowCallback := client.WithProgress(func(notify *protocol.ProgressNotification) {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		if returned {
+			t.Errorf("progress callback after the request returned")
+		}
+		received = append(received, notify.Progress)
+	})
+	check := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		returned = true
+		if len(received) == 0 || received[len(received)-1] != steps {
+			t.Fatalf("%s progress = %v, want the last update %d", name, received, steps)
+		}
+		for i := 1; i < len(received); i++ {
+			if received[i] <= received[i-1] {
+				t.Fatalf("%s progress = %v, want increasing", name, received)
+			}
+		}
+	}
+
+	if _, err = mcpClient.ReadResource(context.Background(), protocol.NewReadResourceRequest(resource.URI), slowCallback, client.WithProgressBuffer(4)); err != nil {
+		t.Fatalf("ReadResource: %v", err)
+	}
+	check("ReadResource")
+
+	mu.Lock()
+	received, returned = nil, false
+	mu.Unlock()
+	if _, err = mcpClient.GetPrompt(context.Background(), protocol.NewGetPromptRequest("slow", nil), slowCallback); err != nil {
+		t.Fatalf("GetPrompt: %v", err)
+	}
+	check("GetPrompt")
+}
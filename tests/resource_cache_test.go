@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/ThinkInAIXYZ/go-mcp/client"
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"github.com/ThinkInAIXYZ/go-mcp/server"
+	"github.com/ThinkInAIXYZ/go-mcp/transport"
+)
+
+func TestResourceCache(t *testing.T) {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	mcpServer, err := server.NewServer(transport.NewMockServerTransport(serverReader, serverWriter), server.WithResourceContentVersions())
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	var (
+		mu          sync.Mutex
+		content     = "v1"
+		conditional int
+	)
+	resource := &protocol.Resource{URI: "config:///app", Name: "config", MimeType: "text/plain"}
+	mcpServer.RegisterResource(resource, func(_ context.Context, request *protocol.ReadResourceRequest) (*protocol.ReadResourceResult, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if request.GetIfNoneMatch() != "" {
+			conditional++
+		}
+		return protocol.NewReadResourceResult([]protocol.ResourceContents{
+			&protocol.TextResourceContents{URI: request.URI, MimeType: "text/plain", Text: content},
+		}), nil
+	})
+	go func() {
+		if err := mcpServer.Run(); err != nil {
+			t.Errorf("server.Run: %v", err)
+		}
+	}()
+	defer mcpServer.Shutdown(context.Background())
+
+	mcpClient, err := client.NewClient(transport.NewMockClientTransport(clientReader, clientWriter), client.WithResourceCache(8))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer mcpClient.Close()
+
+	ctx := context.Background()
+	first, err := mcpClient.ReadResource(ctx, protocol.NewReadResourceRequest(resource.URI))
+	if err != nil || first.GetVersion() == "" {
+		t.Fatalf("ReadResource() = %+v, %v, want a versioned result", first, err)
+	}
+	second, err := mcpClient.ReadResource(ctx, protocol.NewReadResourceRequest(resource.URI))
+	if err != nil {
+		t.Fatalf("ReadResource: %v", err)
+	}
+	mu.Lock()
+	if second != first || conditional != 1 {
+		t.Errorf("second read = %p with %d conditional reads, want the cached %p after 1", second, conditional, first)
+	}
+	content = "v2"
+	mu.Unlock()
+
+	if _, err = mcpClient.SubscribeResourceChange(ctx, protocol.NewSubscribeRequest(resource.URI)); err != nil {
+		t.Fatalf("SubscribeResourceChange: %v", err)
+	}
+	if err = mcpServer.SendNotification4ResourcesUpdated(ctx, &protocol.ResourceUpdatedNotification{URI: resource.URI}); err != nil {
+		t.Fatalf("SendNotification4ResourcesUpdated: %v", err)
+	}
+
+	third, err := mcpClient.ReadResource(ctx, protocol.NewReadResourceRequest(resource.URI))
+	if err != nil {
+		t.Fatalf("ReadResource: %v", err)
+	}
+	if text := third.Contents[0].(*protocol.TextResourceContents).Text; text != "v2" || third.GetVersion() == first.GetVersion() {
+		t.Errorf("ReadResource() = %s with version %s, want the new content", text, third.GetVersion())
+	}
+}
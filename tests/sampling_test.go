@@ -0,0 +1,405 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/client"
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+	"github.com/ThinkInAIXYZ/go-mcp/server"
+	"github.com/ThinkInAIXYZ/go-mcp/transport"
+)
+
+const (
+	// noContentReply makes scriptedSampling reply without content
+	noContentReply = "<no content>"
+	// emptyReply makes scriptedSampling reply with an empty result
+	emptyReply = "<empty>"
+)
+
+type scriptedSampling struct {
+	mu       sync.Mutex
+	replies  []string
+	requests []*protocol.CreateMessageRequest
+}
+
+func (s *scriptedSampling) CreateMessage(_ context.Context, request *protocol.CreateMessageRequest) (*protocol.CreateMessageResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, request)
+	if len(s.replies) == 0 {
+		return nil, errors.New("no reply left")
+	}
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+	if reply == "" {
+		return nil, errors.New("model overloaded")
+	}
+	if reply == noContentReply {
+		return protocol.NewCreateMessageResult(nil, protocol.RoleAssistant, "stub-model", "endTurn"), nil
+	}
+	if reply == emptyReply {
+		return &protocol.CreateMessageResult{}, nil
+	}
+	return protocol.NewCreateMessageResult(&protocol.TextContent{Type: "text", Text: reply}, protocol.RoleAssistant, "stub-model", "endTurn"), nil
+}
+
+func TestSampleJSON(t *testing.T) {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	mcpServer, err := server.NewServer(transport.NewMockServerTransport(serverReader, serverWriter),
+		server.WithDefaultModelPreferences(&protocol.ModelPreferences{SpeedPriority: 0.8}))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	type summary struct {
+		Title string   `json:"title"`
+		Tags  []string `json:"tags"`
+	}
+	var (
+		got       summary
+		sampleErr error
+	)
+	tool := &protocol.Tool{Name: "summarize", InputSchema: protocol.InputSchema{Type: protocol.Object}}
+	mcpServer.RegisterTool(tool, func(ctx context.Context, _ *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+		conversation := server.NewConversation().User("Summarize as JSON").User(map[string]string{"text": "hello world"})
+		got, sampleErr = server.SampleJSON[summary](ctx, mcpServer, conversation,
+			server.WithSamplingModelHints("small-model"),
+			server.WithSamplingRetry(1, time.Millisecond),
+			server.WithSamplingMaxIterations(2))
+		return &protocol.CallToolResult{Content: []protocol.Content{&protocol.TextContent{Type: "text", Text: "done"}}}, nil
+	})
+	go func() {
+		if err := mcpServer.Run(); err != nil {
+			t.Errorf("server.Run: %v", err)
+		}
+	}()
+	defer mcpServer.Shutdown(context.Background())
+
+	handler := &scriptedSampling{replies: []string{"", "not json", "```json\n{\"title\":\"greeting\",\"tags\":[\"hello\"]}\n```", "{}"}}
+	mcpClient, err := client.NewClient(transport.NewMockClientTransport(clientReader, clientWriter), client.WithSamplingHandler(handler))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer mcpClient.Close()
+
+	if _, err = mcpClient.CallTool(context.Background(), protocol.NewCallToolRequest("summarize", map[string]interface{}{})); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if sampleErr != nil || got.Title != "greeting" || len(got.Tags) != 1 {
+		t.Fatalf("SampleJSON() = %+v, %v", got, sampleErr)
+	}
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	// the transient error is retried, the invalid reply is answered with the error
+	if len(handler.requests) != 3 {
+		t.Fatalf("sampling requests = %d, want 3", len(handler.requests))
+	}
+	last := handler.requests[2]
+	if len(last.Messages) != 4 || last.Messages[2].Role != protocol.RoleAssistant || last.Messages[3].Role != protocol.RoleUser {
+		t.Errorf("last request messages = %+v, want the reply and the correction appended", last.Messages)
+	}
+	prefs := last.ModelPreferences
+	if prefs == nil || prefs.SpeedPriority != 0.8 || len(prefs.Hints) != 1 || prefs.Hints[0].Name != "small-model" {
+		t.Errorf("model preferences = %+v, want the server default with the hint", prefs)
+	}
+
+	handler.replies = []string{"no", "still no"}
+	handler.mu.Unlock()
+	if _, err = mcpClient.CallTool(context.Background(), protocol.NewCallToolRequest("summarize", map[string]interface{}{})); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	handler.mu.Lock()
+	if !errors.Is(sampleErr, pkg.ErrSamplingMaxIterations) {
+		t.Errorf("SampleJSON() error = %v, want ErrSamplingMaxIterations", sampleErr)
+	}
+
+	handler.replies = []string{noContentReply}
+	handler.mu.Unlock()
+	if _, err = mcpClient.CallTool(context.Background(), protocol.NewCallToolRequest("summarize", map[string]interface{}{})); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	handler.mu.Lock()
+	if sampleErr == nil || errors.Is(sampleErr, pkg.ErrSamplingMaxIterations) {
+		t.Errorf("SampleJSON() error = %v, want the missing content reported", sampleErr)
+	}
+}
+
+func TestSampleLoopEmptyResult(t *testing.T) {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	mcpServer, err := server.NewServer(transport.NewMockServerTransport(serverReader, serverWriter))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	var (
+		conversation *server.Conversation
+		loopErr      error
+		stepped      bool
+	)
+	tool := &protocol.Tool{Name: "loop", InputSchema: protocol.InputSchema{Type: protocol.Object}}
+	mcpServer.RegisterTool(tool, func(ctx context.Context, _ *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+		conversation = server.NewConversation().User("hello")
+		_, loopErr = mcpServer.SampleLoop(ctx, conversation, func(context.Context, int, *protocol.CreateMessageResult) (interface{}, bool, error) {
+			stepped = true
+			return nil, true, nil
+		})
+		return &protocol.CallToolResult{Content: []protocol.Content{&protocol.TextContent{Type: "text", Text: "done"}}}, nil
+	})
+	go func() {
+		if err := mcpServer.Run(); err != nil {
+			t.Errorf("server.Run: %v", err)
+		}
+	}()
+	defer mcpServer.Shutdown(context.Background())
+
+	handler := &scriptedSampling{replies: []string{emptyReply}}
+	mcpClient, err := client.NewClient(transport.NewMockClientTransport(clientReader, clientWriter), client.WithSamplingHandler(handler))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer mcpClient.Close()
+
+	if _, err = mcpClient.CallTool(context.Background(), protocol.NewCallToolRequest("loop", map[string]interface{}{})); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if loopErr == nil || stepped {
+		t.Errorf("SampleLoop() error = %v, stepped = %v, want the missing content reported", loopErr, stepped)
+	}
+	if len(conversation.Messages) != 1 {
+		t.Errorf("conversation = %d messages, want the empty reply left out", len(conversation.Messages))
+	}
+}
+
+func TestSamplingPolicy(t *testing.T) {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	mcpServer, err := server.NewServer(transport.NewMockServerTransport(serverReader, serverWriter))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	var (
+		mu      sync.Mutex
+		results []error
+	)
+	requests := []*protocol.CreateMessageRequest{
+		protocol.NewCreateMessageRequest(nil, 500, protocol.WithModelPreferences(&protocol.ModelPreferences{
+			Hints: []protocol.ModelHint{{Name: "expensive-model"}, {Name: "small-model"}},
+		})),
+		protocol.NewCreateMessageRequest(nil, 100, protocol.WithIncludeContext("allServers")),
+		protocol.NewCreateMessageRequest(nil, 100, protocol.WithSystemPrompt("reject me")),
+		protocol.NewCreateMessageRequest(nil, 100),
+		protocol.NewCreateMessageRequest(nil, 100),
+	}
+	tool := &protocol.Tool{Name: "sample", InputSchema: protocol.InputSchema{Type: protocol.Object}}
+	mcpServer.RegisterTool(tool, func(ctx context.Context, _ *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+		for _, request := range requests {
+			request.Messages = []*protocol.SamplingMessage{{Role: protocol.RoleUser, Content: &protocol.TextContent{Type: "text", Text: "hi"}}}
+			_, err := mcpServer.Sampling(ctx, request)
+			mu.Lock()
+			results = append(results, err)
+			mu.Unlock()
+		}
+		return &protocol.CallToolResult{Content: []protocol.Content{&protocol.TextContent{Type: "text", Text: "done"}}}, nil
+	})
+	go func() {
+		if err := mcpServer.Run(); err != nil {
+			t.Errorf("server.Run: %v", err)
+		}
+	}()
+	defer mcpServer.Shutdown(context.Background())
+
+	handler := &scriptedSampling{replies: []string{"a", "b", "c"}}
+	mcpClient, err := client.NewClient(transport.NewMockClientTransport(clientReader, clientWriter),
+		client.WithSamplingHandler(handler),
+		client.WithSamplingPolicy(
+			client.WithMaxTokensPerRequest(200),
+			client.WithMaxTokensPerSession(250),
+			client.WithAllowedModelHints("small-model"),
+			client.WithAllowedIncludeContext("none", "thisServer"),
+			client.WithSamplingApproval(func(_ context.Context, request *protocol.CreateMessageRequest) (*protocol.CreateMessageRequest, error) {
+				if request.SystemPrompt == "reject me" {
+					return nil, errors.New("not today")
+				}
+				return request, nil
+			}),
+		))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer mcpClient.Close()
+
+	if _, err = mcpClient.CallTool(context.Background(), protocol.NewCallToolRequest("sample", map[string]interface{}{})); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	wantCodes := []int{0, protocol.InvalidParams, protocol.SamplingRejected, 0, protocol.InvalidParams}
+	for i, want := range wantCodes {
+		var respErr *pkg.ResponseError
+		switch {
+		case want == 0 && results[i] != nil:
+			t.Errorf("request %d error = %v, want success", i, results[i])
+		case want != 0 && (!errors.As(results[i], &respErr) || respErr.Code != want):
+			t.Errorf("request %d error = %v, want code %d", i, results[i], want)
+		}
+	}
+
+	handler.mu.Lock()
+	first, second := handler.requests[0], handler.requests[1]
+	handler.mu.Unlock()
+	if first.MaxTokens != 200 || len(first.ModelPreferences.Hints) != 1 || first.ModelPreferences.Hints[0].Name != "small-model" {
+		t.Errorf("first request = %+v, want maxTokens 200 and only the allowed hint", first)
+	}
+	if second.MaxTokens != 50 {
+		t.Errorf("second request maxTokens = %d, want the remaining 50 of the session budget", second.MaxTokens)
+	}
+
+	usage := mcpClient.SamplingUsage()
+	if usage.Requests != 2 || usage.Rejected != 3 || usage.Tokens != 250 || usage.Models["stub-model"] != 2 {
+		t.Errorf("SamplingUsage() = %+v", usage)
+	}
+}
+
+type progressSampling struct {
+	client *client.Client
+}
+
+func (s *progressSampling) CreateMessage(ctx context.Context, _ *protocol.CreateMessageRequest) (*protocol.CreateMessageResult, error) {
+	for i := 1; i <= 3; i++ {
+		if err := s.client.SendProgressNotification(ctx, protocol.NewProgressNotification(float64(i), 3, "waiting for approval")); err != nil {
+			return nil, err
+		}
+	}
+	return protocol.NewCreateMessageResult(&protocol.TextContent{Type: "text", Text: "ok"}, protocol.RoleAssistant, "stub-model", "endTurn"), nil
+}
+
+func TestSamplingWithProgressChan(t *testing.T) {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	mcpServer, err := server.NewServer(transport.NewMockServerTransport(serverReader, serverWriter))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	var progress []float64
+	tool := &protocol.Tool{Name: "sample", InputSchema: protocol.InputSchema{Type: protocol.Object}}
+	mcpServer.RegisterTool(tool, func(ctx context.Context, _ *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+		progressCh := make(chan *protocol.ProgressNotification, 10)
+		request := protocol.NewCreateMessageRequest([]*protocol.SamplingMessage{
+			{Role: protocol.RoleUser, Content: &protocol.TextContent{Type: "text", Text: "hi"}},
+		}, 100)
+		meta := map[string]interface{}{"trace": "1"}
+		request.Meta = meta
+		if _, err := mcpServer.SamplingWithProgressChan(ctx, request, progressCh); err != nil {
+			return nil, err
+		}
+		if _, ok := meta[protocol.ProgressTokenKey]; ok || len(meta) != 1 {
+			t.Errorf("request meta = %v, want it left untouched", meta)
+		}
+		for notify := range progressCh {
+			progress = append(progress, notify.Progress)
+		}
+		return &protocol.CallToolResult{Content: []protocol.Content{&protocol.TextContent{Type: "text", Text: "done"}}}, nil
+	})
+	go func() {
+		if err := mcpServer.Run(); err != nil {
+			t.Errorf("server.Run: %v", err)
+		}
+	}()
+	defer mcpServer.Shutdown(context.Background())
+
+	handler := &progressSampling{}
+	mcpClient, err := client.NewClient(transport.NewMockClientTransport(clientReader, clientWriter), client.WithSamplingHandler(handler))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer mcpClient.Close()
+	handler.client = mcpClient
+
+	if _, err = mcpClient.CallTool(context.Background(), protocol.NewCallToolRequest("sample", map[string]interface{}{})); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if len(progress) != 3 || progress[2] != 3 {
+		t.Errorf("progress = %v, want 1, 2, 3", progress)
+	}
+}
+
+type blockingSampling struct {
+	cancelled chan error
+}
+
+func (s *blockingSampling) CreateMessage(ctx context.Context, _ *protocol.CreateMessageRequest) (*protocol.CreateMessageResult, error) {
+	select {
+	case <-ctx.Done():
+		s.cancelled <- ctx.Err()
+		return nil, ctx.Err()
+	case <-time.After(5 * time.Second):
+		s.cancelled <- nil
+		return protocol.NewCreateMessageResult(&protocol.TextContent{Type: "text", Text: "late"}, protocol.RoleAssistant, "stub-model", "endTurn"), nil
+	}
+}
+
+func TestSamplingCancellation(t *testing.T) {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	mcpServer, err := server.NewServer(transport.NewMockServerTransport(serverReader, serverWriter))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	var sampleErr error
+	tool := &protocol.Tool{Name: "sample", InputSchema: protocol.InputSchema{Type: protocol.Object}}
+	mcpServer.RegisterTool(tool, func(ctx context.Context, _ *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		_, sampleErr = mcpServer.Sampling(ctx, protocol.NewCreateMessageRequest([]*protocol.SamplingMessage{
+			{Role: protocol.RoleUser, Content: &protocol.TextContent{Type: "text", Text: "hi"}},
+		}, 100))
+		return &protocol.CallToolResult{Content: []protocol.Content{&protocol.TextContent{Type: "text", Text: "done"}}}, nil
+	})
+	go func() {
+		if err := mcpServer.Run(); err != nil {
+			t.Errorf("server.Run: %v", err)
+		}
+	}()
+	defer mcpServer.Shutdown(context.Background())
+
+	handler := &blockingSampling{cancelled: make(chan error, 1)}
+	mcpClient, err := client.NewClient(transport.NewMockClientTransport(clientReader, clientWriter), client.WithSamplingHandler(handler))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer mcpClient.Close()
+
+	if _, err = mcpClient.CallTool(context.Background(), protocol.NewCallToolRequest("sample", map[string]interface{}{})); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if !errors.Is(sampleErr, context.DeadlineExceeded) {
+		t.Errorf("Sampling() error = %v, want DeadlineExceeded", sampleErr)
+	}
+	select {
+	case err = <-handler.cancelled:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("sampling handler ctx error = %v, want Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("sampling handler was not cancelled")
+	}
+}
@@ -0,0 +1,175 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+)
+
+// Framing is how messages are delimited on the byte streams of the stdio and stream transports.
+type Framing int32
+
+const (
+	// FramingAuto reads both framings, detected on the first message of the peer, and writes the framing
+	// of the peer once it is known, newline-delimited JSON until then.
+	FramingAuto Framing = iota
+	// FramingNewline delimits messages with '\n', they must not contain one.
+	FramingNewline
+	// FramingContentLength precedes each message with a Content-Length header, as language servers do.
+	FramingContentLength
+)
+
+const contentLengthHeader = "Content-Length"
+
+// framer reads and writes the messages of one byte stream.
+type framer struct {
+	framing int32
+	// maxMessageSize limits the size of a read message, 0 for no limit
+	maxMessageSize int64
+}
+
+func newFramer(framing Framing, maxMessageSize int64) *framer {
+	return &framer{framing: int32(framing), maxMessageSize: maxMessageSize}
+}
+
+func (f *framer) current() Framing {
+	return Framing(atomic.LoadInt32(&f.framing))
+}
+
+// readMessage returns the next non-empty message. A message over the maximum size is skipped
+// and reported with pkg.ErrMessageTooLarge, reading can go on. Other errors are final.
+func (f *framer) readMessage(r *bufio.Reader) ([]byte, error) {
+	framing := f.current()
+	if framing == FramingAuto {
+		if err := skipSpace(r); err != nil {
+			return nil, err
+		}
+		framing = FramingNewline
+		// the header framed messages of language servers begin with their Content-Length
+		if first, _ := r.Peek(1); len(first) == 1 && (first[0] == 'C' || first[0] == 'c') {
+			if prefix, _ := r.Peek(len(contentLengthHeader)); strings.EqualFold(string(prefix), contentLengthHeader) {
+				framing = FramingContentLength
+			}
+		}
+		atomic.CompareAndSwapInt32(&f.framing, int32(FramingAuto), int32(framing))
+	}
+
+	if framing == FramingContentLength {
+		return f.readContentLength(r)
+	}
+	return f.readLine(r)
+}
+
+func (f *framer) readLine(r *bufio.Reader) ([]byte, error) {
+	for {
+		var (
+			line    []byte
+			tooLong bool
+		)
+		for {
+			chunk, err := r.ReadSlice('\n')
+			if !tooLong {
+				line = append(line, chunk...)
+				if f.maxMessageSize > 0 && int64(len(line)) > f.maxMessageSize+2 {
+					tooLong, line = true, nil
+				}
+			}
+			if err == bufio.ErrBufferFull {
+				continue
+			}
+			if err != nil && (err != io.EOF || len(line) == 0 || tooLong) {
+				return nil, err
+			}
+			break
+		}
+		if tooLong {
+			return nil, fmt.Errorf("%w: line longer than %d bytes", pkg.ErrMessageTooLarge, f.maxMessageSize)
+		}
+
+		line = bytes.TrimRight(line, "\r\n")
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if f.maxMessageSize > 0 && int64(len(line)) > f.maxMessageSize {
+			return nil, fmt.Errorf("%w: %d bytes, limit %d", pkg.ErrMessageTooLarge, len(line), f.maxMessageSize)
+		}
+		return line, nil
+	}
+}
+
+func (f *framer) readContentLength(r *bufio.Reader) ([]byte, error) {
+	length := int64(-1)
+	for {
+		line, err := r.ReadSlice('\n')
+		if err != nil {
+			if err == bufio.ErrBufferFull {
+				return nil, fmt.Errorf("header line too long")
+			}
+			return nil, err
+		}
+		header := strings.TrimSpace(string(line))
+		if header == "" {
+			if length < 0 {
+				// blank lines between messages
+				continue
+			}
+			break
+		}
+		name, value, ok := strings.Cut(header, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid header %q", header)
+		}
+		if strings.EqualFold(strings.TrimSpace(name), contentLengthHeader) {
+			if length, err = strconv.ParseInt(strings.TrimSpace(value), 10, 64); err != nil || length < 0 {
+				return nil, fmt.Errorf("invalid %s %q", contentLengthHeader, value)
+			}
+		}
+	}
+
+	if f.maxMessageSize > 0 && length > f.maxMessageSize {
+		if _, err := io.CopyN(io.Discard, r, length); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %d bytes, limit %d", pkg.ErrMessageTooLarge, length, f.maxMessageSize)
+	}
+
+	return readAnnounced(r, length)
+}
+
+// writeMessage writes msg in one Write, callers serialize the writes of a stream
+func (f *framer) writeMessage(w io.Writer, msg []byte) error {
+	var frame []byte
+	if f.current() == FramingContentLength {
+		header := fmt.Sprintf("%s: %d\r\n\r\n", contentLengthHeader, len(msg))
+		frame = make([]byte, 0, len(header)+len(msg))
+		frame = append(frame, header...)
+		frame = append(frame, msg...)
+	} else {
+		frame = make([]byte, 0, len(msg)+1)
+		frame = append(frame, msg...)
+		frame = append(frame, mcpMessageDelimiter)
+	}
+	_, err := w.Write(frame)
+	return err
+}
+
+// skipSpace discards the whitespace before the next message
+func skipSpace(r *bufio.Reader) error {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return r.UnreadByte()
+	}
+}
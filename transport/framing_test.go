@@ -0,0 +1,110 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+)
+
+func TestFramerReadMessage(t *testing.T) {
+	tests := []struct {
+		name           string
+		framing        Framing
+		maxMessageSize int64
+		input          string
+		want           []string
+		wantFraming    Framing
+	}{
+		{
+			name:        "auto detects newline",
+			framing:     FramingAuto,
+			input:       "\n{\"id\":1}\r\n  \n{\"id\":2}",
+			want:        []string{`{"id":1}`, `{"id":2}`},
+			wantFraming: FramingNewline,
+		},
+		{
+			name:        "auto detects content length",
+			framing:     FramingAuto,
+			input:       "Content-Length: 8\r\n\r\n{\"id\":1}\r\ncontent-length: 15\r\nContent-Type: application/json\r\n\r\n{\n  \"id\": 2\n}\n\n",
+			want:        []string{`{"id":1}`, "{\n  \"id\": 2\n}\n\n"},
+			wantFraming: FramingContentLength,
+		},
+		{
+			name:           "newline skips oversized messages",
+			framing:        FramingNewline,
+			maxMessageSize: 8,
+			input:          "{\"id\":1}\n{\"id\":\"large\"}\n{\"id\":3}\n",
+			want:           []string{`{"id":1}`, "too large", `{"id":3}`},
+			wantFraming:    FramingNewline,
+		},
+		{
+			name:           "content length skips oversized messages",
+			framing:        FramingContentLength,
+			maxMessageSize: 8,
+			input:          "Content-Length: 14\r\n\r\n{\"id\":\"large\"}Content-Length: 8\r\n\r\n{\"id\":3}",
+			want:           []string{"too large", `{"id":3}`},
+			wantFraming:    FramingContentLength,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFramer(tt.framing, tt.maxMessageSize)
+			r := bufio.NewReader(strings.NewReader(tt.input))
+
+			var got []string
+			for {
+				msg, err := f.readMessage(r)
+				if errors.Is(err, pkg.ErrMessageTooLarge) {
+					got = append(got, "too large")
+					continue
+				}
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("readMessage() error = %v", err)
+				}
+				got = append(got, string(msg))
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("readMessage() got %q, want %q", got, tt.want)
+			}
+			if f.current() != tt.wantFraming {
+				t.Fatalf("framing = %v, want %v", f.current(), tt.wantFraming)
+			}
+		})
+	}
+}
+
+func TestFramerReadMessageUntrustedLength(t *testing.T) {
+	// without a limit, a length of 1 TiB announced for a few bytes fails without allocating it
+	f := newFramer(FramingContentLength, 0)
+	r := bufio.NewReader(strings.NewReader("Content-Length: 1099511627776\r\n\r\n{\"id\":1}"))
+	if _, err := f.readMessage(r); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("readMessage() error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestFramerWriteMessage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newFramer(FramingContentLength, 0).writeMessage(&buf, []byte(`{"id":1}`)); err != nil {
+		t.Fatalf("writeMessage() error = %v", err)
+	}
+	if want := "Content-Length: 8\r\n\r\n{\"id\":1}"; buf.String() != want {
+		t.Fatalf("writeMessage() wrote %q, want %q", buf.String(), want)
+	}
+}
+
+func TestStreamTransportContentLength(t *testing.T) {
+	clientSide, serverSide := net.Pipe()
+
+	// the server answers in the framing of the client
+	testTransport(t, NewStreamClientTransport(clientSide, WithStreamClientOptionFraming(FramingContentLength)),
+		NewStreamServerTransport(serverSide, serverSide))
+}
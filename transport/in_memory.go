@@ -0,0 +1,190 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+)
+
+// inMemoryPipe carries the messages between the two ends of NewInMemoryPair
+type inMemoryPipe struct {
+	toServer chan Message
+	toClient chan Message
+
+	clientClosed    chan struct{}
+	clientCloseOnce sync.Once
+	serverClosed    chan struct{}
+	serverCloseOnce sync.Once
+}
+
+// NewInMemoryPair returns a client and a server transport linked in-process: messages are handed over
+// as they are, without being written to and parsed from a stream. The server serves a single session.
+func NewInMemoryPair() (ClientTransport, ServerTransport) {
+	p := &inMemoryPipe{
+		toServer:     make(chan Message),
+		toClient:     make(chan Message),
+		clientClosed: make(chan struct{}),
+		serverClosed: make(chan struct{}),
+	}
+	return &inMemoryClientTransport{pipe: p, logger: pkg.DefaultLogger, receiveShutDone: make(chan struct{})},
+		&inMemoryServerTransport{pipe: p, logger: pkg.DefaultLogger, receiveShutDone: make(chan struct{})}
+}
+
+type inMemoryClientTransport struct {
+	pipe     *inMemoryPipe
+	receiver clientReceiver
+
+	logger pkg.Logger
+
+	cancel          context.CancelFunc
+	receiveShutDone chan struct{}
+}
+
+func (t *inMemoryClientTransport) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+
+	go func() {
+		defer pkg.Recover()
+		defer close(t.receiveShutDone)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.pipe.serverClosed:
+				t.receiver.Interrupt(fmt.Errorf("in-memory server closed: %w", io.ErrClosedPipe))
+				return
+			case msg := <-t.pipe.toClient:
+				if err := t.receiver.Receive(ctx, msg); err != nil {
+					t.logger.Errorf("receiver failed: %v", err)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (t *inMemoryClientTransport) Send(ctx context.Context, msg Message) error {
+	select {
+	case t.pipe.toServer <- msg:
+		return nil
+	case <-t.pipe.clientClosed:
+		return io.ErrClosedPipe
+	case <-t.pipe.serverClosed:
+		return io.ErrClosedPipe
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *inMemoryClientTransport) SetReceiver(receiver clientReceiver) {
+	t.receiver = receiver
+}
+
+func (t *inMemoryClientTransport) Close() error {
+	t.pipe.clientCloseOnce.Do(func() {
+		close(t.pipe.clientClosed)
+	})
+
+	if t.cancel != nil {
+		t.cancel()
+		<-t.receiveShutDone
+	}
+	return nil
+}
+
+type inMemoryServerTransport struct {
+	pipe     *inMemoryPipe
+	receiver serverReceiver
+
+	sessionManager sessionManager
+	sessionID      string
+
+	logger pkg.Logger
+
+	receiveShutDone chan struct{}
+}
+
+func (t *inMemoryServerTransport) Run() error {
+	defer close(t.receiveShutDone)
+
+	t.sessionID = t.sessionManager.CreateSession(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for {
+		select {
+		case <-t.pipe.serverClosed:
+			return nil
+		case <-t.pipe.clientClosed:
+			// like the end of stdin, the client is gone
+			return nil
+		case msg := <-t.pipe.toServer:
+			t.receive(ctx, msg)
+		}
+	}
+}
+
+func (t *inMemoryServerTransport) Send(ctx context.Context, _ string, msg Message) error {
+	select {
+	case t.pipe.toClient <- msg:
+		return nil
+	case <-t.pipe.serverClosed:
+		return io.ErrClosedPipe
+	case <-t.pipe.clientClosed:
+		return io.ErrClosedPipe
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *inMemoryServerTransport) SetReceiver(receiver serverReceiver) {
+	t.receiver = receiver
+}
+
+func (t *inMemoryServerTransport) SetSessionManager(m sessionManager) {
+	t.sessionManager = m
+}
+
+func (t *inMemoryServerTransport) Shutdown(userCtx context.Context, serverCtx context.Context) error {
+	t.pipe.serverCloseOnce.Do(func() {
+		close(t.pipe.serverClosed)
+	})
+
+	select {
+	case <-t.receiveShutDone:
+		return nil
+	case <-serverCtx.Done():
+		return nil
+	case <-userCtx.Done():
+		return userCtx.Err()
+	}
+}
+
+func (t *inMemoryServerTransport) receive(ctx context.Context, msg []byte) {
+	outputMsgCh, err := t.receiver.Receive(ctx, t.sessionID, msg)
+	if err != nil {
+		t.logger.Errorf("receiver failed: %v", err)
+		return
+	}
+
+	if outputMsgCh == nil {
+		return
+	}
+
+	go func() {
+		defer pkg.Recover()
+
+		for msg := range outputMsgCh {
+			if e := t.Send(context.Background(), t.sessionID, msg); e != nil {
+				t.logger.Errorf("Failed to send message: %v", e)
+			}
+		}
+	}()
+}
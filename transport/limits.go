@@ -0,0 +1,61 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+)
+
+const (
+	// defaultMaxMessageSize limits the messages received by transports unless configured otherwise
+	defaultMaxMessageSize = 16 << 20
+	// announcedChunk is the most allocated for a message before its bytes arrive
+	announcedChunk = 64 << 10
+)
+
+// readAnnounced reads the length bytes a peer announced, the buffer grows with what is actually read
+// so that a length announced without the bytes to back it allocates nothing
+func readAnnounced(r io.Reader, length int64) ([]byte, error) {
+	capacity := length
+	if capacity > announcedChunk {
+		capacity = announcedChunk
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, capacity))
+	if _, err := io.CopyN(buf, r, length); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readBody reads the body of r, failing with pkg.ErrMessageTooLarge past limit bytes, 0 for no limit
+func readBody(r *http.Request, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r.Body)
+	}
+	if r.ContentLength > limit {
+		return nil, fmt.Errorf("%w: %d bytes, limit %d", pkg.ErrMessageTooLarge, r.ContentLength, limit)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes", pkg.ErrMessageTooLarge, limit)
+	}
+	return body, nil
+}
+
+// messageTooLargeResponse answers a message skipped for its size, whose id is unknown
+func messageTooLargeResponse(err error) Message {
+	msg, _ := json.Marshal(protocol.NewJSONRPCErrorResponse(nil, protocol.InvalidRequest, err.Error()))
+	return msg
+}
@@ -0,0 +1,103 @@
+package transport
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// corsAllowedHeaders are the request headers browsers may send cross-origin
+const corsAllowedHeaders = "Accept, Authorization, Content-Type, Last-Event-ID, Mcp-Protocol-Version, " + sessionIDHeader
+
+// originGuard protects the HTTP server transports from DNS rebinding and unwanted cross-origin requests,
+// and grants the allowed origins access through CORS.
+//
+// With no allowed hosts, the Host of requests received on a loopback address must be a loopback name,
+// so that a hostile name resolving to 127.0.0.1 can't reach a local server. Requests with no Origin come
+// from other clients than browsers and are accepted. Browsers may call from the server's own origin,
+// from a loopback origin when the server is local, or from an allowed origin.
+type originGuard struct {
+	// allowedOrigins are the allowed origins of cross-origin requests, "*" for any
+	allowedOrigins []string
+	// allowedHosts are the Host values, with or without port, requests may be sent to
+	allowedHosts []string
+}
+
+// check validates the Host and Origin of r, and sets the CORS headers of the response for an allowed origin
+func (g *originGuard) check(w http.ResponseWriter, r *http.Request) error {
+	local := isLoopbackRequest(r)
+
+	hostname := (&url.URL{Host: r.Host}).Hostname()
+	if len(g.allowedHosts) > 0 {
+		if !containsFold(g.allowedHosts, r.Host) && !containsFold(g.allowedHosts, hostname) {
+			return fmt.Errorf("host %q not allowed", r.Host)
+		}
+	} else if local && !isLoopbackHost(hostname) {
+		return fmt.Errorf("host %q not allowed on a local server", r.Host)
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	if !g.originAllowed(origin, r.Host, local) {
+		return fmt.Errorf("origin %q not allowed", origin)
+	}
+
+	h := w.Header()
+	h.Set("Access-Control-Allow-Origin", origin)
+	h.Add("Vary", "Origin")
+	h.Set("Access-Control-Expose-Headers", sessionIDHeader)
+	return nil
+}
+
+func (g *originGuard) originAllowed(origin, host string, local bool) bool {
+	if containsFold(g.allowedOrigins, "*") || containsFold(g.allowedOrigins, origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		// including the opaque origin "null" of sandboxed frames and local files
+		return false
+	}
+	return strings.EqualFold(u.Host, host) || (local && isLoopbackHost(u.Hostname()))
+}
+
+// preflight answers the CORS preflight request of a checked origin
+func (g *originGuard) preflight(w http.ResponseWriter, methods string) {
+	h := w.Header()
+	h.Set("Access-Control-Allow-Methods", methods)
+	h.Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+	h.Set("Access-Control-Max-Age", "86400")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isLoopbackRequest reports whether r was received on a loopback address
+func isLoopbackRequest(r *http.Request) bool {
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok {
+		return false
+	}
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	return ok && tcpAddr.IP.IsLoopback()
+}
+
+func isLoopbackHost(hostname string) bool {
+	hostname = strings.ToLower(hostname)
+	if hostname == "localhost" || strings.HasSuffix(hostname, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(hostname)
+	return ip != nil && ip.IsLoopback()
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
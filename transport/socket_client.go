@@ -0,0 +1,157 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+)
+
+type SocketClientTransportOption func(*socketClientTransport)
+
+func WithSocketClientOptionLogger(log pkg.Logger) SocketClientTransportOption {
+	return func(t *socketClientTransport) {
+		t.logger = log
+	}
+}
+
+func WithSocketClientOptionDialTimeout(timeout time.Duration) SocketClientTransportOption {
+	return func(t *socketClientTransport) {
+		t.dialTimeout = timeout
+	}
+}
+
+// WithSocketClientOptionFraming sets how messages are delimited, FramingNewline by default.
+func WithSocketClientOptionFraming(framing Framing) SocketClientTransportOption {
+	return func(t *socketClientTransport) {
+		t.framer.framing = int32(framing)
+	}
+}
+
+// WithSocketClientOptionMaxMessageSize limits the size of a received message, larger ones are skipped.
+// 16 MiB by default, 0 for no limit.
+func WithSocketClientOptionMaxMessageSize(size int64) SocketClientTransportOption {
+	return func(t *socketClientTransport) {
+		t.framer.maxMessageSize = size
+	}
+}
+
+type socketClientTransport struct {
+	network string
+	addr    string
+
+	conn     net.Conn
+	receiver clientReceiver
+
+	framer  *framer
+	writeMu sync.Mutex
+
+	logger      pkg.Logger
+	dialTimeout time.Duration
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+// NewSocketClientTransport returns transport that connects to a socket server listening on network and addr,
+// eg. a long-lived daemon shared by several hosts.
+func NewSocketClientTransport(network, addr string, opts ...SocketClientTransportOption) (ClientTransport, error) {
+	t := &socketClientTransport{
+		network:     network,
+		addr:        addr,
+		framer:      newFramer(FramingNewline, defaultMaxMessageSize),
+		logger:      pkg.DefaultLogger,
+		dialTimeout: 10 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t, nil
+}
+
+func (t *socketClientTransport) Start() error {
+	conn, err := net.DialTimeout(t.network, t.addr, t.dialTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	t.conn = conn
+
+	innerCtx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+
+	t.wg.Add(1)
+	go func() {
+		defer pkg.Recover()
+		defer t.wg.Done()
+
+		t.startReceive(innerCtx)
+	}()
+
+	return nil
+}
+
+func (t *socketClientTransport) Send(_ context.Context, msg Message) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if err := t.framer.writeMessage(t.conn, msg); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+	return nil
+}
+
+func (t *socketClientTransport) SetReceiver(receiver clientReceiver) {
+	t.receiver = receiver
+}
+
+func (t *socketClientTransport) Close() error {
+	// without a successful Start there is no connection
+	if t.cancel == nil {
+		return nil
+	}
+	t.cancel()
+
+	if err := t.conn.Close(); err != nil {
+		return fmt.Errorf("failed to close connection: %w", err)
+	}
+
+	t.wg.Wait()
+
+	return nil
+}
+
+func (t *socketClientTransport) startReceive(ctx context.Context) {
+	s := bufio.NewReader(t.conn)
+
+	for {
+		line, err := t.framer.readMessage(s)
+		if err != nil {
+			if errors.Is(err, pkg.ErrMessageTooLarge) {
+				t.logger.Errorf("skip received message: %v", err)
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			t.receiver.Interrupt(fmt.Errorf("socket read error: %w", err))
+
+			if !errors.Is(err, io.EOF) {
+				t.logger.Errorf("socket read error: %+v", err)
+			}
+			return
+		}
+
+		if err = t.receiver.Receive(ctx, line); err != nil {
+			t.logger.Errorf("receiver failed: %v", err)
+		}
+	}
+}
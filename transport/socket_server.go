@@ -0,0 +1,264 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+)
+
+type SocketServerTransportOption func(*socketServerTransport)
+
+func WithSocketServerOptionLogger(log pkg.Logger) SocketServerTransportOption {
+	return func(t *socketServerTransport) {
+		t.logger = log
+	}
+}
+
+// WithSocketServerOptionMaxMessageSize limits the size of a received message, larger ones are skipped and answered
+// with a JSON-RPC error. 16 MiB by default, 0 for no limit.
+func WithSocketServerOptionMaxMessageSize(size int64) SocketServerTransportOption {
+	return func(t *socketServerTransport) {
+		t.maxMessageSize = size
+	}
+}
+
+type socketServerTransport struct {
+	// ctx is the context that controls the lifecycle of the server
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	network string
+	addr    string
+
+	mu       sync.Mutex
+	listener net.Listener
+	closed   bool
+
+	inFlySend sync.WaitGroup
+	// connections tracks the connections being served
+	connections sync.WaitGroup
+
+	receiver serverReceiver
+
+	sessionManager sessionManager
+
+	logger         pkg.Logger
+	maxMessageSize int64
+}
+
+// NewSocketServerTransport returns transport that listens on network ("tcp", "unix", ...) and addr
+// and speaks the framings of the stdio transport, detected on each connection, which is a session.
+func NewSocketServerTransport(network, addr string, opts ...SocketServerTransportOption) ServerTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t := &socketServerTransport{
+		ctx:     ctx,
+		cancel:  cancel,
+		network: network,
+		addr:    addr,
+		logger:  pkg.DefaultLogger,
+
+		maxMessageSize: defaultMaxMessageSize,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func (t *socketServerTransport) Run() error {
+	listener, err := net.Listen(t.network, t.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		_ = listener.Close()
+		return nil
+	}
+	t.listener = listener
+	t.mu.Unlock()
+
+	fmt.Printf("starting mcp server at %s://%s\n", t.network, listener.Addr())
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.logger.Warnf("socket accept: %v", err)
+				continue
+			}
+			return fmt.Errorf("failed to accept: %w", err)
+		}
+
+		t.connections.Add(1)
+		go func() {
+			defer pkg.Recover()
+			defer t.connections.Done()
+
+			t.serve(conn)
+		}()
+	}
+}
+
+func (t *socketServerTransport) Send(ctx context.Context, sessionID string, msg Message) error {
+	t.inFlySend.Add(1)
+	defer t.inFlySend.Done()
+
+	select {
+	case <-t.ctx.Done():
+		return t.ctx.Err()
+	default:
+		return t.sessionManager.EnqueueMessageForSend(ctx, sessionID, msg)
+	}
+}
+
+func (t *socketServerTransport) SetReceiver(receiver serverReceiver) {
+	t.receiver = receiver
+}
+
+func (t *socketServerTransport) SetSessionManager(m sessionManager) {
+	t.sessionManager = m
+}
+
+// serve reads the messages of one connection and writes the messages queued for its session.
+func (t *socketServerTransport) serve(conn net.Conn) {
+	defer conn.Close()
+
+	sessionID := t.sessionManager.CreateSession(t.ctx)
+	defer t.sessionManager.CloseSession(sessionID)
+
+	if err := t.sessionManager.OpenMessageQueueForSend(sessionID); err != nil {
+		t.logger.Errorf("socket sessionID=%s OpenMessageQueueForSend fail: %v", sessionID, err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(t.ctx)
+	defer cancel()
+
+	// the replies follow the framing of the client
+	f := newFramer(FramingAuto, t.maxMessageSize)
+	writeDone := make(chan struct{})
+	go func() {
+		defer pkg.Recover()
+		defer close(writeDone)
+		// unblock the reader once nothing can be written anymore
+		defer conn.Close()
+
+		for {
+			msg, err := t.sessionManager.DequeueMessageForSend(ctx, sessionID)
+			if err != nil {
+				if !errors.Is(err, pkg.ErrSendEOF) && !errors.Is(err, context.Canceled) {
+					t.logger.Debugf("socket dequeueMessage err: %+v, sessionID=%s", err, sessionID)
+				}
+				return
+			}
+			if err = f.writeMessage(conn, msg); err != nil {
+				t.logger.Errorf("Failed to write message: %v", err)
+				return
+			}
+		}
+	}()
+
+	s := bufio.NewReader(conn)
+	for {
+		line, err := f.readMessage(s)
+		if err != nil {
+			if errors.Is(err, pkg.ErrMessageTooLarge) {
+				t.logger.Errorf("skip received message: %v, sessionID=%s", err, sessionID)
+				if e := t.Send(ctx, sessionID, messageTooLargeResponse(err)); e != nil {
+					t.logger.Errorf("Failed to send message: %v", e)
+				}
+				continue
+			}
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				t.logger.Errorf("socket read error: %v, sessionID=%s", err, sessionID)
+			}
+			break
+		}
+		t.receive(ctx, sessionID, line)
+	}
+
+	cancel()
+	<-writeDone
+}
+
+func (t *socketServerTransport) receive(ctx context.Context, sessionID string, line []byte) {
+	outputMsgCh, err := t.receiver.Receive(ctx, sessionID, line)
+	if err != nil {
+		t.logger.Errorf("receiver failed: %v", err)
+		return
+	}
+
+	if outputMsgCh == nil {
+		return
+	}
+
+	go func() {
+		defer pkg.Recover()
+
+		for msg := range outputMsgCh {
+			if e := t.Send(context.Background(), sessionID, msg); e != nil {
+				t.logger.Errorf("Failed to send message: %v", e)
+			}
+		}
+	}()
+}
+
+// Shutdown stops accepting connections, then once serverCtx is done closes the sessions,
+// which closes their connections, and waits for them until userCtx is done.
+func (t *socketServerTransport) Shutdown(userCtx context.Context, serverCtx context.Context) error {
+	t.mu.Lock()
+	t.closed = true
+	listener := t.listener
+	t.mu.Unlock()
+
+	var err error
+	if listener != nil {
+		err = listener.Close()
+	}
+
+	select {
+	case <-serverCtx.Done():
+	case <-userCtx.Done():
+		return userCtx.Err()
+	}
+
+	t.cancel()
+
+	t.inFlySend.Wait()
+
+	t.sessionManager.CloseAllSessions()
+
+	done := make(chan struct{})
+	go func() {
+		defer pkg.Recover()
+		defer close(done)
+
+		t.connections.Wait()
+	}()
+
+	select {
+	case <-done:
+	case <-userCtx.Done():
+		return userCtx.Err()
+	}
+
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		return fmt.Errorf("failed to close listener: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSocket(t *testing.T) {
+	port, err := getAvailablePort()
+	if err != nil {
+		t.Fatalf("Failed to get available port: %v", err)
+	}
+	serverAddr := fmt.Sprintf("127.0.0.1:%d", port)
+
+	client, err := NewSocketClientTransport("tcp", serverAddr)
+	if err != nil {
+		t.Fatalf("NewSocketClientTransport failed: %v", err)
+	}
+
+	testTransport(t, client, NewSocketServerTransport("tcp", serverAddr))
+}
+
+func TestUnixSocket(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "mcp.sock")
+
+	client, err := NewSocketClientTransport("unix", addr)
+	if err != nil {
+		t.Fatalf("NewSocketClientTransport failed: %v", err)
+	}
+
+	testTransport(t, client, NewSocketServerTransport("unix", addr))
+}
+
+func TestSocketContentLength(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "mcp.sock")
+
+	// the server answers in the framing of the client
+	client, err := NewSocketClientTransport("unix", addr, WithSocketClientOptionFraming(FramingContentLength))
+	if err != nil {
+		t.Fatalf("NewSocketClientTransport failed: %v", err)
+	}
+
+	testTransport(t, client, NewSocketServerTransport("unix", addr))
+}
+
+func TestSocketClientCloseWithoutStart(t *testing.T) {
+	client, err := NewSocketClientTransport("tcp", "127.0.0.1:1")
+	if err != nil {
+		t.Fatalf("NewSocketClientTransport() failed: %v", err)
+	}
+	if err = client.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+}
+
+func TestSocketClientMaxMessageSize(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "mcp.sock")
+	listener, err := net.Listen("unix", addr)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte(strings.Repeat("x", 100) + "\n" + `{"id":1}` + "\n"))
+		_, _ = io.Copy(io.Discard, conn)
+	}()
+
+	client, err := NewSocketClientTransport("unix", addr, WithSocketClientOptionMaxMessageSize(16))
+	if err != nil {
+		t.Fatalf("NewSocketClientTransport failed: %v", err)
+	}
+	received := make(chan string, 2)
+	client.SetReceiver(NewClientReceiver(func(_ context.Context, msg []byte) error {
+		received <- string(msg)
+		return nil
+	}, func(error) {}))
+	if err = client.Start(); err != nil {
+		t.Fatalf("client.Start() failed: %v", err)
+	}
+	defer client.Close()
+
+	// the oversized line is skipped
+	select {
+	case msg := <-received:
+		if msg != `{"id":1}` {
+			t.Fatalf("received %q, want the message after the oversized one", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("message after the oversized one not received")
+	}
+}
+
+func TestSocketShutdown(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "mcp.sock")
+
+	svr := NewSocketServerTransport("unix", addr)
+	svr.SetSessionManager(newMockSessionManager())
+	svr.SetReceiver(ServerReceiverF(func(context.Context, string, []byte) (<-chan []byte, error) {
+		return nil, nil
+	}))
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- svr.Run()
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	interrupted := make(chan error, 1)
+	client, err := NewSocketClientTransport("unix", addr)
+	if err != nil {
+		t.Fatalf("NewSocketClientTransport failed: %v", err)
+	}
+	client.SetReceiver(NewClientReceiver(func(context.Context, []byte) error {
+		return nil
+	}, func(err error) {
+		interrupted <- err
+	}))
+	if err = client.Start(); err != nil {
+		t.Fatalf("client.Start() failed: %v", err)
+	}
+	defer client.Close()
+
+	userCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	serverCtx, cancelServer := context.WithCancel(userCtx)
+	cancelServer()
+	if err = svr.Shutdown(userCtx, serverCtx); err != nil {
+		t.Fatalf("server.Shutdown() failed: %v", err)
+	}
+
+	if err = <-runErr; err != nil {
+		t.Fatalf("server.Run() = %v, want nil after shutdown", err)
+	}
+	select {
+	case <-interrupted:
+	case <-time.After(time.Second):
+		t.Fatalf("the connection was not closed by shutdown")
+	}
+}
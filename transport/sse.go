@@ -0,0 +1,170 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"time"
+)
+
+// sseEvent is an event of a text/event-stream
+type sseEvent struct {
+	// id is the last event ID of the stream when the event was dispatched
+	id string
+	// event is the type of the event, "message" unless set
+	event string
+	data  []byte
+	// retry is the reconnection time last set by the stream, 0 if none
+	retry time.Duration
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// sseDecoder reads the events of a text/event-stream as the HTML Living Standard parses them:
+// lines end with CRLF, LF or CR, data fields accumulate over lines, comments and unknown fields
+// are ignored, and an event cut by the end of the stream is not dispatched.
+type sseDecoder struct {
+	reader *bufio.Reader
+
+	started   bool // the leading BOM, if any, was skipped
+	pendingCR bool // the last line ended with CR, a LF right after belongs to it
+
+	lastEventID string
+	retry       time.Duration
+}
+
+func newSSEDecoder(r io.Reader) *sseDecoder {
+	return &sseDecoder{reader: bufio.NewReader(r)}
+}
+
+// next returns the next event, io.EOF at the end of the stream
+func (d *sseDecoder) next() (*sseEvent, error) {
+	var (
+		event   string
+		data    []byte
+		hasData bool
+	)
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) == 0 {
+			if !hasData {
+				event = ""
+				continue
+			}
+			if event == "" {
+				event = "message"
+			}
+			// the data fields are joined with LF, without a trailing one
+			return &sseEvent{id: d.lastEventID, event: event, data: data[:len(data)-1], retry: d.retry}, nil
+		}
+		if line[0] == ':' {
+			continue
+		}
+
+		field, value := line, []byte(nil)
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], line[i+1:]
+			if len(value) > 0 && value[0] == ' ' {
+				value = value[1:]
+			}
+		}
+		switch string(field) {
+		case "event":
+			event = string(value)
+		case "data":
+			data = append(append(data, value...), '\n')
+			hasData = true
+		case "id":
+			if bytes.IndexByte(value, 0) < 0 {
+				d.lastEventID = string(value)
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(string(value), 10, 63); err == nil {
+				d.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+// readLine returns the next line without its end, the last line of the stream only when it is ended
+func (d *sseDecoder) readLine() ([]byte, error) {
+	if !d.started {
+		d.started = true
+		if prefix, _ := d.reader.Peek(len(utf8BOM)); bytes.Equal(prefix, utf8BOM) {
+			_, _ = d.reader.Discard(len(utf8BOM))
+		}
+	}
+	if d.pendingCR {
+		d.pendingCR = false
+		if b, err := d.reader.Peek(1); err == nil && b[0] == '\n' {
+			_, _ = d.reader.Discard(1)
+		}
+	}
+
+	var line []byte
+	for {
+		n := d.reader.Buffered()
+		if n == 0 {
+			n = 1
+		}
+		buf, err := d.reader.Peek(n)
+		if i := bytes.IndexAny(buf, "\r\n"); i >= 0 {
+			line = append(line, buf[:i]...)
+			d.pendingCR = buf[i] == '\r'
+			_, _ = d.reader.Discard(i + 1)
+			return line, nil
+		}
+		line = append(line, buf...)
+		_, _ = d.reader.Discard(len(buf))
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// writeSSEEvent writes event in one Write, its data split into as many data fields as it has lines,
+// so that line breaks in the data can't end the event early.
+func writeSSEEvent(w io.Writer, event *sseEvent) error {
+	var buf bytes.Buffer
+	if event.id != "" {
+		buf.WriteString("id: " + event.id + "\n")
+	}
+	if event.event != "" {
+		buf.WriteString("event: " + event.event + "\n")
+	}
+	if event.retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(event.retry.Milliseconds(), 10) + "\n")
+	}
+
+	data := event.data
+	for {
+		i := bytes.IndexAny(data, "\r\n")
+		if i < 0 {
+			break
+		}
+		buf.WriteString("data: ")
+		buf.Write(data[:i])
+		buf.WriteByte('\n')
+		if data[i] == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+			i++
+		}
+		data = data[i+1:]
+	}
+	buf.WriteString("data: ")
+	buf.Write(data)
+	buf.WriteString("\n\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeSSEComment writes a comment, which clients ignore, to keep a stream alive
+func writeSSEComment(w io.Writer, comment string) error {
+	_, err := io.WriteString(w, ": "+comment+"\n\n")
+	return err
+}
@@ -1,15 +1,15 @@
 package transport
 
 import (
-	"bufio"
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"strings"
 	"time"
 
 	"github.com/ThinkInAIXYZ/go-mcp/pkg"
@@ -35,6 +35,29 @@ func WithSSEClientOptionLogger(log pkg.Logger) SSEClientTransportOption {
 	}
 }
 
+// WithSSEClientOptionTLSConfig sets the TLS configuration of the connections, completed by the other TLS options.
+func WithSSEClientOptionTLSConfig(config *tls.Config) SSEClientTransportOption {
+	return func(t *sseClientTransport) {
+		t.tls.config = config
+	}
+}
+
+// WithSSEClientOptionClientCertificate authenticates to servers requiring mutual TLS with the certificate and key
+// of the given files, loaded again whenever they change.
+func WithSSEClientOptionClientCertificate(certFile, keyFile string) SSEClientTransportOption {
+	return func(t *sseClientTransport) {
+		t.tls.certFile = certFile
+		t.tls.keyFile = keyFile
+	}
+}
+
+// WithSSEClientOptionRootCAs sets the authorities server certificates are verified against, the system ones by default.
+func WithSSEClientOptionRootCAs(pool *x509.CertPool) SSEClientTransportOption {
+	return func(t *sseClientTransport) {
+		t.tls.rootCAs = pool
+	}
+}
+
 func WithRetryFunc(retry func(func() error)) SSEClientTransportOption {
 	return func(t *sseClientTransport) {
 		t.retry = retry
@@ -55,6 +78,7 @@ type sseClientTransport struct {
 	logger         pkg.Logger
 	receiveTimeout time.Duration
 	client         *http.Client
+	tls            clientTLS
 
 	retry func(func() error)
 
@@ -90,6 +114,10 @@ func NewSSEClientTransport(serverURL string, opts ...SSEClientTransportOption) (
 		opt(t)
 	}
 
+	if t.client, err = t.tls.httpClient(t.client); err != nil {
+		return nil, err
+	}
+
 	return t, nil
 }
 
@@ -165,18 +193,10 @@ func (t *sseClientTransport) readSSE(reader io.ReadCloser) error {
 		_ = reader.Close()
 	}()
 
-	br := bufio.NewReader(reader)
-	var event, data string
-
+	decoder := newSSEDecoder(reader)
 	for {
-		line, err := br.ReadString('\n')
+		event, err := decoder.next()
 		if err != nil {
-			if err == io.EOF {
-				// Process any pending event before exit
-				if event != "" && data != "" {
-					t.handleSSEEvent(event, data)
-				}
-			}
 			select {
 			case <-t.ctx.Done():
 				return t.ctx.Err()
@@ -184,24 +204,7 @@ func (t *sseClientTransport) readSSE(reader io.ReadCloser) error {
 				return fmt.Errorf("SSE stream error: %w", err)
 			}
 		}
-
-		// Remove only newline markers
-		line = strings.TrimRight(line, "\r\n")
-		if line == "" {
-			// Empty line means end of event
-			if event != "" && data != "" {
-				t.handleSSEEvent(event, data)
-				event = ""
-				data = ""
-			}
-			continue
-		}
-
-		if strings.HasPrefix(line, "event:") {
-			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
-		} else if strings.HasPrefix(line, "data:") {
-			data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
-		}
+		t.handleSSEEvent(event.event, string(event.data))
 	}
 }
 
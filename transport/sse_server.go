@@ -2,9 +2,10 @@ package transport
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"path"
@@ -41,6 +42,54 @@ func WithSSEServerTransportOptionURLPrefix(urlPrefix string) SSEServerTransportO
 	}
 }
 
+// WithSSEServerTransportOptionMaxMessageSize limits the size of a posted message, larger ones are refused
+// with 413 Request Entity Too Large. 16 MiB by default, 0 for no limit.
+func WithSSEServerTransportOptionMaxMessageSize(size int64) SSEServerTransportOption {
+	return func(t *sseServerTransport) {
+		t.maxMessageSize = size
+	}
+}
+
+// WithSSEServerTransportOptionAllowedOrigins sets the origins, as "https://app.example.com", browsers may call the server from
+// besides its own, "*" for any. Loopback origins are allowed too when the server is bound to a loopback address.
+func WithSSEServerTransportOptionAllowedOrigins(origins ...string) SSEServerTransportOption {
+	return func(t *sseServerTransport) {
+		t.originGuard.allowedOrigins = origins
+	}
+}
+
+// WithSSEServerTransportOptionAllowedHosts sets the Host values, with or without port, the server answers to. By default
+// any Host is accepted, but a loopback name only when the server is bound to a loopback address.
+func WithSSEServerTransportOptionAllowedHosts(hosts ...string) SSEServerTransportOption {
+	return func(t *sseServerTransport) {
+		t.originGuard.allowedHosts = hosts
+	}
+}
+
+// WithSSEServerTransportOptionTLS serves over TLS with the certificate and key of the given files,
+// loaded again whenever they change so that renewed certificates are used without a restart.
+func WithSSEServerTransportOptionTLS(certFile, keyFile string) SSEServerTransportOption {
+	return func(t *sseServerTransport) {
+		t.tls.certFile = certFile
+		t.tls.keyFile = keyFile
+	}
+}
+
+// WithSSEServerTransportOptionTLSConfig serves over TLS with config, completed by the other TLS options.
+func WithSSEServerTransportOptionTLSConfig(config *tls.Config) SSEServerTransportOption {
+	return func(t *sseServerTransport) {
+		t.tls.config = config
+	}
+}
+
+// WithSSEServerTransportOptionClientCAs requires clients to authenticate with a certificate issued by one of pool,
+// handlers get it with GetClientCertificateFromCtx.
+func WithSSEServerTransportOptionClientCAs(pool *x509.CertPool) SSEServerTransportOption {
+	return func(t *sseServerTransport) {
+		t.tls.clientCAs = pool
+	}
+}
+
 type SSEServerTransportAndHandlerOption func(*sseServerTransport)
 
 func WithSSEServerTransportAndHandlerOptionCopyParamKeys(paramsKey []string) SSEServerTransportAndHandlerOption {
@@ -55,6 +104,24 @@ func WithSSEServerTransportAndHandlerOptionLogger(logger pkg.Logger) SSEServerTr
 	}
 }
 
+func WithSSEServerTransportAndHandlerOptionMaxMessageSize(size int64) SSEServerTransportAndHandlerOption {
+	return func(t *sseServerTransport) {
+		t.maxMessageSize = size
+	}
+}
+
+func WithSSEServerTransportAndHandlerOptionAllowedOrigins(origins ...string) SSEServerTransportAndHandlerOption {
+	return func(t *sseServerTransport) {
+		t.originGuard.allowedOrigins = origins
+	}
+}
+
+func WithSSEServerTransportAndHandlerOptionAllowedHosts(hosts ...string) SSEServerTransportAndHandlerOption {
+	return func(t *sseServerTransport) {
+		t.originGuard.allowedHosts = hosts
+	}
+}
+
 type sseServerTransport struct {
 	// ctx is the context that controls the lifecycle of the SSE server.
 	// It is used to coordinate cancellation of all ongoing send operations when the server is shutting down.
@@ -74,11 +141,14 @@ type sseServerTransport struct {
 	sessionManager sessionManager
 
 	// options
-	logger        pkg.Logger
-	ssePath       string
-	messagePath   string
-	urlPrefix     string
-	copyParamKeys []string
+	logger         pkg.Logger
+	ssePath        string
+	messagePath    string
+	urlPrefix      string
+	copyParamKeys  []string
+	maxMessageSize int64
+	originGuard    originGuard
+	tls            serverTLS
 }
 
 type SSEHandler struct {
@@ -105,12 +175,13 @@ func NewSSEServerTransport(addr string, opts ...SSEServerTransportOption) (Serve
 	ctx, cancel := context.WithCancel(context.Background())
 
 	t := &sseServerTransport{
-		ctx:         ctx,
-		cancel:      cancel,
-		logger:      pkg.DefaultLogger,
-		ssePath:     "/sse",
-		messagePath: "/message",
-		urlPrefix:   "",
+		ctx:            ctx,
+		cancel:         cancel,
+		logger:         pkg.DefaultLogger,
+		ssePath:        "/sse",
+		messagePath:    "/message",
+		urlPrefix:      "",
+		maxMessageSize: defaultMaxMessageSize,
 	}
 	for _, opt := range opts {
 		opt(t)
@@ -163,6 +234,7 @@ func NewSSEServerTransportAndHandler(messageEndpointURL string,
 		cancel:             cancel,
 		messageEndpointURL: messageEndpointURL,
 		logger:             pkg.DefaultLogger,
+		maxMessageSize:     defaultMaxMessageSize,
 	}
 	for _, opt := range opts {
 		opt(t)
@@ -177,9 +249,9 @@ func (t *sseServerTransport) Run() error {
 		return nil
 	}
 
-	fmt.Printf("starting mcp server at http://%s%s\n", t.httpSvr.Addr, t.ssePath)
+	fmt.Printf("starting mcp server at %s://%s%s\n", t.tls.scheme("http"), t.httpSvr.Addr, t.ssePath)
 
-	if err := t.httpSvr.ListenAndServe(); err != nil {
+	if err := t.tls.listenAndServe(t.httpSvr); err != nil {
 		return fmt.Errorf("failed to start HTTP server: %w", err)
 	}
 	return nil
@@ -211,6 +283,15 @@ func (t *sseServerTransport) handleSSE(w http.ResponseWriter, r *http.Request) {
 		t.writeError(w, http.StatusInternalServerError, "Internal server error")
 	})
 
+	if err := t.originGuard.check(w, r); err != nil {
+		t.writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if r.Method == http.MethodOptions {
+		t.originGuard.preflight(w, "GET, OPTIONS")
+		return
+	}
+
 	// Set headers for SSE
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -235,7 +316,7 @@ func (t *sseServerTransport) handleSSE(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Send the initial endpoint event
-	if _, err := fmt.Fprintf(w, "event: endpoint\ndata: %s\n\n", uri); err != nil {
+	if err := writeSSEEvent(w, &sseEvent{event: "endpoint", data: []byte(uri)}); err != nil {
 		t.logger.Errorf("send endpoint message fail")
 		return
 	}
@@ -258,7 +339,7 @@ func (t *sseServerTransport) handleSSE(w http.ResponseWriter, r *http.Request) {
 
 		t.logger.Debugf("Sending message: %s", string(msg))
 
-		if _, err = fmt.Fprintf(w, "event: message\ndata: %s\n\n", msg); err != nil {
+		if err = writeSSEEvent(w, &sseEvent{event: "message", data: msg}); err != nil {
 			t.logger.Errorf("Failed to write message: %v", err)
 			continue
 		}
@@ -273,6 +354,15 @@ func (t *sseServerTransport) handleMessage(w http.ResponseWriter, r *http.Reques
 		t.writeError(w, http.StatusInternalServerError, "Internal server error")
 	})
 
+	if err := t.originGuard.check(w, r); err != nil {
+		t.writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if r.Method == http.MethodOptions {
+		t.originGuard.preflight(w, "POST, OPTIONS")
+		return
+	}
+
 	if r.Method != http.MethodPost {
 		t.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
@@ -285,13 +375,17 @@ func (t *sseServerTransport) handleMessage(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Parse message as raw JSON
-	inputMsg, err := io.ReadAll(r.Body)
+	inputMsg, err := readBody(r, t.maxMessageSize)
 	if err != nil {
+		if errors.Is(err, pkg.ErrMessageTooLarge) {
+			t.writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
 		t.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
 		return
 	}
 
-	outputMsgCh, err := t.receiver.Receive(r.Context(), sessionID, inputMsg)
+	outputMsgCh, err := t.receiver.Receive(setClientCertificateToCtx(r.Context(), r), sessionID, inputMsg)
 	if err != nil {
 		t.writeError(w, http.StatusBadRequest, fmt.Sprintf("Failed to receive: %v", err))
 		return
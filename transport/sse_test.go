@@ -1,11 +1,15 @@
 package transport
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
 	"net/url"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -220,3 +224,99 @@ func Test_sseClientTransport_handleSSEEvent(t1 *testing.T) {
 		})
 	}
 }
+
+func Test_sseDecoder(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []sseEvent
+	}{
+		{
+			name:   "multi_line_data",
+			stream: "data: {\"a\":\ndata:  1}\n\n",
+			want:   []sseEvent{{event: "message", data: []byte("{\"a\":\n 1}")}},
+		},
+		{
+			name:   "event_type_and_comments",
+			stream: ": heartbeat\n\nevent: endpoint\n: comment\ndata: /message\n\n",
+			want:   []sseEvent{{event: "endpoint", data: []byte("/message")}},
+		},
+		{
+			name:   "bom_and_line_ends",
+			stream: "\xEF\xBB\xBFdata: a\r\n\r\ndata: b\r\rdata: c\n\n",
+			want: []sseEvent{
+				{event: "message", data: []byte("a")},
+				{event: "message", data: []byte("b")},
+				{event: "message", data: []byte("c")},
+			},
+		},
+		{
+			name:   "id_and_retry",
+			stream: "id: 1\nretry: 3000\ndata: a\n\nretry: x\ndata: b\n\nid\ndata: c\n\n",
+			want: []sseEvent{
+				{id: "1", event: "message", data: []byte("a"), retry: 3 * time.Second},
+				{id: "1", event: "message", data: []byte("b"), retry: 3 * time.Second},
+				{id: "", event: "message", data: []byte("c"), retry: 3 * time.Second},
+			},
+		},
+		{
+			name:   "no_data_no_event",
+			stream: "event: endpoint\n\ndata\n\n",
+			want:   []sseEvent{{event: "message", data: []byte("")}},
+		},
+		{
+			name:   "unknown_fields_and_no_space",
+			stream: "foo: bar\ndata:a\n\n",
+			want:   []sseEvent{{event: "message", data: []byte("a")}},
+		},
+		{
+			name:   "cut_event_not_dispatched",
+			stream: "data: a\n\ndata: b\n",
+			want:   []sseEvent{{event: "message", data: []byte("a")}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := newSSEDecoder(strings.NewReader(tt.stream))
+			var got []sseEvent
+			for {
+				event, err := decoder.next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("next() error = %v", err)
+				}
+				got = append(got, *event)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_writeSSEEvent(t *testing.T) {
+	event := &sseEvent{id: "7", event: "message", data: []byte("{\n  \"a\": 1\r\n}"), retry: time.Second}
+
+	var buf bytes.Buffer
+	if err := writeSSEEvent(&buf, event); err != nil {
+		t.Fatalf("writeSSEEvent() error = %v", err)
+	}
+	if err := writeSSEComment(&buf, "heartbeat"); err != nil {
+		t.Fatalf("writeSSEComment() error = %v", err)
+	}
+	want := "id: 7\nevent: message\nretry: 1000\ndata: {\ndata:   \"a\": 1\ndata: }\n\n: heartbeat\n\n"
+	if buf.String() != want {
+		t.Fatalf("stream = %q, want %q", buf.String(), want)
+	}
+
+	got, err := newSSEDecoder(&buf).next()
+	if err != nil {
+		t.Fatalf("next() error = %v", err)
+	}
+	// line breaks come back as LF
+	if string(got.data) != "{\n  \"a\": 1\n}" || got.id != event.id || got.retry != event.retry {
+		t.Errorf("decoded = %+v", got)
+	}
+}
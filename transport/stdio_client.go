@@ -9,9 +9,24 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
 
 	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+	"github.com/ThinkInAIXYZ/go-mcp/protocol"
+)
+
+const (
+	defaultStopGracePeriod = 5 * time.Second
+	defaultStderrRetention = 32
+	// outputDrainTimeout bounds the reading of the output left by an exited process
+	outputDrainTimeout = time.Second
+	// minRestartBackoff keeps a process that keeps crashing from being restarted in a tight loop
+	minRestartBackoff        = 100 * time.Millisecond
+	defaultMaxRestartBackoff = 30 * time.Second
 )
 
 type StdioClientTransportOption func(*stdioClientTransport)
@@ -28,18 +43,165 @@ func WithStdioClientOptionEnv(env ...string) StdioClientTransportOption {
 	}
 }
 
+// WithStdioClientOptionDir sets the working directory of the server process.
+func WithStdioClientOptionDir(dir string) StdioClientTransportOption {
+	return func(t *stdioClientTransport) {
+		t.cmd.Dir = dir
+	}
+}
+
+// WithStdioClientOptionStopGracePeriod sets how long Close waits for the server process to exit after
+// closing its stdin, and again after SIGTERM, before killing it. 5 seconds by default.
+func WithStdioClientOptionStopGracePeriod(grace time.Duration) StdioClientTransportOption {
+	return func(t *stdioClientTransport) {
+		t.stopGracePeriod = grace
+	}
+}
+
+// WithStdioClientOptionProcessGroup starts the server process in a process group of its own
+// and signals the whole group when stopping it, so that the children it spawned are stopped too.
+// Not supported on windows.
+func WithStdioClientOptionProcessGroup() StdioClientTransportOption {
+	return func(t *stdioClientTransport) {
+		t.processGroup = true
+	}
+}
+
+// WithStdioClientOptionRestartPolicy restarts the server process when it exits unexpectedly, see RestartPolicy.
+func WithStdioClientOptionRestartPolicy(policy RestartPolicy) StdioClientTransportOption {
+	return func(t *stdioClientTransport) {
+		t.restartPolicy = &policy
+	}
+}
+
+// WithStdioClientOptionStderrSink receives the stderr output of the server process line by line,
+// by default it is logged as errors. Lines are truncated to the maximum message size.
+func WithStdioClientOptionStderrSink(sink func(line []byte)) StdioClientTransportOption {
+	return func(t *stdioClientTransport) {
+		t.stderrSink = sink
+	}
+}
+
+// WithStdioClientOptionStderrRetention sets how many of the last stderr lines are kept for ProcessExitError, 32 by default.
+func WithStdioClientOptionStderrRetention(lines int) StdioClientTransportOption {
+	return func(t *stdioClientTransport) {
+		t.stderr = newLineRing(lines)
+	}
+}
+
+// WithStdioClientOptionOnExit is called when the server process exits unexpectedly, before any restart.
+func WithStdioClientOptionOnExit(onExit func(*ProcessExitError)) StdioClientTransportOption {
+	return func(t *stdioClientTransport) {
+		t.onExit = onExit
+	}
+}
+
+// WithStdioClientOptionFraming sets how messages are delimited, FramingAuto by default.
+// Servers that only speak Content-Length framing need FramingContentLength, as the client writes first.
+func WithStdioClientOptionFraming(framing Framing) StdioClientTransportOption {
+	return func(t *stdioClientTransport) {
+		t.framing = framing
+	}
+}
+
+// WithStdioClientOptionMaxMessageSize limits the size of a received message, larger ones are skipped.
+// 16 MiB by default, 0 for no limit.
+func WithStdioClientOptionMaxMessageSize(size int64) StdioClientTransportOption {
+	return func(t *stdioClientTransport) {
+		t.maxMessageSize = size
+	}
+}
+
+// RestartPolicy restarts a server process that exited while the transport was in use.
+// Requests in flight when it exited fail, the client initializes the new process before its next request.
+type RestartPolicy struct {
+	// MaxRestarts limits the restarts over the life of the transport, negative for no limit
+	MaxRestarts int
+	// InitialBackoff is the delay before the first restart, doubled for each following one up to MaxBackoff.
+	// At least 100ms.
+	InitialBackoff time.Duration
+	// MaxBackoff is 30s when zero
+	MaxBackoff time.Duration
+}
+
+func (p *RestartPolicy) backoff(restarts int) time.Duration {
+	maxDelay := p.MaxBackoff
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxRestartBackoff
+	}
+	delay := p.InitialBackoff
+	if delay < minRestartBackoff {
+		delay = minRestartBackoff
+	}
+	// stops doubling at the cap, so that many restarts cannot overflow the delay
+	for i := 0; i < restarts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// ProcessExitError reports a server process that exited while the transport was in use,
+// with the last lines it wrote to stderr.
+type ProcessExitError struct {
+	// Err is the error returned by exec.Cmd.Wait, nil for a zero exit status
+	Err    error
+	Stderr []string
+}
+
+func (e *ProcessExitError) Error() string {
+	msg := "server process exited"
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if len(e.Stderr) > 0 {
+		msg += ", stderr:\n" + strings.Join(e.Stderr, "\n")
+	}
+	return msg
+}
+
+func (e *ProcessExitError) Unwrap() error {
+	return e.Err
+}
+
 const mcpMessageDelimiter = '\n'
 
 type stdioClientTransport struct {
+	// mu guards the process, which is replaced on restart
+	mu        sync.Mutex
 	cmd       *exec.Cmd
-	receiver  clientReceiver
 	reader    io.Reader
 	writer    io.WriteCloser
 	errReader io.Reader
-
-	logger pkg.Logger
+	// framer is renewed with the process, which may frame messages differently
+	framer *framer
+	// exited is closed once the process has exited, done once its output is read too,
+	// exitErr is then its exit status
+	exited  chan struct{}
+	done    chan struct{}
+	exitErr error
+	// restarted rejects messages with pkg.ErrSessionClosed until the client initializes the new process
+	restarted bool
+	closed    bool
+
+	writeMu sync.Mutex
+
+	receiver clientReceiver
+
+	logger          pkg.Logger
+	framing         Framing
+	maxMessageSize  int64
+	stopGracePeriod time.Duration
+	processGroup    bool
+	restartPolicy   *RestartPolicy
+	stderrSink      func(line []byte)
+	stderr          *lineRing
+	onExit          func(*ProcessExitError)
 
 	wg     sync.WaitGroup
+	ctx    context.Context
 	cancel context.CancelFunc
 }
 
@@ -48,112 +210,337 @@ func NewStdioClientTransport(command string, args []string, opts ...StdioClientT
 
 	cmd.Env = os.Environ()
 
-	stdin, err := cmd.StdinPipe()
+	t := &stdioClientTransport{
+		cmd:             cmd,
+		logger:          pkg.DefaultLogger,
+		maxMessageSize:  defaultMaxMessageSize,
+		stopGracePeriod: defaultStopGracePeriod,
+		stderr:          newLineRing(defaultStderrRetention),
+	}
+	if err := t.setPipes(); err != nil {
+		return nil, err
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.stderrSink == nil {
+		t.stderrSink = func(line []byte) {
+			t.logger.Errorf("receive server error: %s", line)
+		}
+	}
+	return t, nil
+}
+
+// setPipes connects the pipes of t.cmd, which has not been started
+func (t *stdioClientTransport) setPipes() error {
+	stdin, err := t.cmd.StdinPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+		return fmt.Errorf("failed to create stdin pipe: %w", err)
 	}
 
-	stdout, err := cmd.StdoutPipe()
+	stdout, err := t.cmd.StdoutPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+		return fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
 
-	stderr, err := cmd.StderrPipe()
+	stderr, err := t.cmd.StderrPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+		return fmt.Errorf("failed to create stderr pipe: %w", err)
 	}
 
-	t := &stdioClientTransport{
-		cmd:       cmd,
-		reader:    stdout,
-		writer:    stdin,
-		errReader: stderr,
+	t.reader, t.writer, t.errReader = stdout, stdin, stderr
+	return nil
+}
 
-		logger: pkg.DefaultLogger,
-	}
+func (t *stdioClientTransport) Start() error {
+	t.ctx, t.cancel = context.WithCancel(context.Background())
 
-	for _, opt := range opts {
-		opt(t)
+	t.mu.Lock()
+	err := t.startProcessLocked()
+	t.mu.Unlock()
+	if err != nil {
+		t.cancel()
+		return err
 	}
-	return t, nil
+
+	t.wg.Add(1)
+	go func() {
+		defer pkg.Recover()
+		defer t.wg.Done()
+
+		t.supervise()
+	}()
+
+	return nil
 }
 
-func (t *stdioClientTransport) Start() error {
+// startProcessLocked starts t.cmd and the goroutines reading its output and waiting for its exit
+func (t *stdioClientTransport) startProcessLocked() error {
+	if t.processGroup {
+		setProcessGroup(t.cmd)
+	}
 	if err := t.cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start command: %w", err)
 	}
 
-	innerCtx, cancel := context.WithCancel(context.Background())
-	t.cancel = cancel
+	cmd, reader, errReader, writer := t.cmd, t.reader, t.errReader, t.writer
+	f := newFramer(t.framing, t.maxMessageSize)
+	t.framer = f
+	exited, done := make(chan struct{}), make(chan struct{})
+	t.exited, t.done, t.exitErr = exited, done, nil
 
-	t.wg.Add(1)
+	var readers sync.WaitGroup
+	readers.Add(2)
+	t.wg.Add(3)
 	go func() {
 		defer pkg.Recover()
 		defer t.wg.Done()
+		defer readers.Done()
 
-		t.startReceive(innerCtx)
+		t.startReceive(t.ctx, f, reader)
+	}()
+
+	go func() {
+		defer pkg.Recover()
+		defer t.wg.Done()
+		defer readers.Done()
+
+		t.startReceiveErr(errReader)
 	}()
 
-	t.wg.Add(1)
 	go func() {
 		defer pkg.Recover()
 		defer t.wg.Done()
 
-		t.startReceiveErr(innerCtx)
+		// the process is waited for on its own, its pipes stay open as long as a child inherited them
+		err := waitProcess(cmd.Process)
+		t.mu.Lock()
+		t.exitErr = err
+		t.mu.Unlock()
+		close(exited)
+
+		t.drainOutput(&readers, reader, errReader)
+		_ = writer.Close()
+		close(done)
+	}()
+
+	return nil
+}
+
+// waitProcess waits for p to exit, returning an *exec.ExitError as exec.Cmd.Wait does when it failed
+func waitProcess(p *os.Process) error {
+	state, err := p.Wait()
+	if err != nil {
+		return err
+	}
+	if !state.Success() {
+		return &exec.ExitError{ProcessState: state}
+	}
+	return nil
+}
+
+// drainOutput lets the readers of an exited process read what is left in its pipes,
+// then closes the pipes so that a child of the process holding them can't keep the readers alive.
+func (t *stdioClientTransport) drainOutput(readers *sync.WaitGroup, pipes ...io.Reader) {
+	drained := make(chan struct{})
+	go func() {
+		readers.Wait()
+		close(drained)
 	}()
 
+	timer := time.NewTimer(outputDrainTimeout)
+	defer timer.Stop()
+	select {
+	case <-drained:
+	case <-timer.C:
+		t.logger.Warnf("server process output still open after its exit, closing it")
+	}
+
+	for _, pipe := range pipes {
+		if closer, ok := pipe.(io.Closer); ok {
+			_ = closer.Close()
+		}
+	}
+	<-drained
+}
+
+// supervise reports the unexpected exits of the process and restarts it as the restart policy allows
+func (t *stdioClientTransport) supervise() {
+	restarts := 0
+	for {
+		t.mu.Lock()
+		done := t.done
+		t.mu.Unlock()
+
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-done:
+		}
+		select {
+		case <-t.ctx.Done():
+			// stopped by Close
+			return
+		default:
+		}
+
+		t.mu.Lock()
+		exitErr := &ProcessExitError{Err: t.exitErr, Stderr: t.stderr.lines()}
+		t.mu.Unlock()
+
+		t.logger.Errorf("%v", exitErr)
+		if t.onExit != nil {
+			t.onExit(exitErr)
+		}
+		t.receiver.Interrupt(exitErr)
+
+		for {
+			if t.restartPolicy == nil || (t.restartPolicy.MaxRestarts >= 0 && restarts >= t.restartPolicy.MaxRestarts) {
+				return
+			}
+
+			timer := time.NewTimer(t.restartPolicy.backoff(restarts))
+			restarts++
+			select {
+			case <-t.ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			err := t.restart()
+			if err == nil {
+				break
+			}
+			t.logger.Errorf("restart server process fail: %v", err)
+		}
+	}
+}
+
+func (t *stdioClientTransport) restart() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return errors.New("transport closed")
+	}
+
+	prev := t.cmd
+	t.cmd = exec.Command(prev.Path, prev.Args[1:]...) //nolint:gosec
+	t.cmd.Env, t.cmd.Dir = prev.Env, prev.Dir
+	if err := t.setPipes(); err != nil {
+		return err
+	}
+	if err := t.startProcessLocked(); err != nil {
+		return err
+	}
+	t.restarted = true
+
+	t.logger.Infof("server process restarted, pid=%d", t.cmd.Process.Pid)
 	return nil
 }
 
 func (t *stdioClientTransport) Send(_ context.Context, msg Message) error {
-	_, err := t.writer.Write(append(msg, mcpMessageDelimiter))
-	return err
+	t.mu.Lock()
+	if t.restarted {
+		if gjson.GetBytes(msg, "method").String() != string(protocol.Initialize) {
+			t.mu.Unlock()
+			return pkg.ErrSessionClosed
+		}
+		t.restarted = false
+	}
+	writer, f, exited := t.writer, t.framer, t.exited
+	t.mu.Unlock()
+
+	select {
+	case <-exited:
+		return errors.New("server process is not running")
+	default:
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	return f.writeMessage(writer, msg)
 }
 
 func (t *stdioClientTransport) SetReceiver(receiver clientReceiver) {
 	t.receiver = receiver
 }
 
+// Close closes the stdin of the server process and waits for it to exit. A process still running
+// after the grace period gets SIGTERM, then after another grace period SIGKILL, Close gives up
+// waiting for it a grace period later.
 func (t *stdioClientTransport) Close() error {
+	t.mu.Lock()
+	t.closed = true
+	cmd, writer, exited := t.cmd, t.writer, t.exited
+	t.mu.Unlock()
+
 	t.cancel()
 
-	if err := t.writer.Close(); err != nil {
+	if err := writer.Close(); err != nil {
 		return fmt.Errorf("failed to close writer: %w", err)
 	}
 
-	if err := t.cmd.Wait(); err != nil {
-		return err
+	stopped := t.waitExit(exited)
+	if !stopped {
+		t.logger.Warnf("server process still running after %v, sending SIGTERM", t.stopGracePeriod)
+		if err := terminateProcess(cmd.Process, t.processGroup); err != nil {
+			t.logger.Warnf("terminate server process: %v", err)
+		}
+		if !t.waitExit(exited) {
+			t.logger.Warnf("server process still running after SIGTERM, killing it")
+			if err := killProcess(cmd.Process, t.processGroup); err != nil {
+				t.logger.Warnf("kill server process: %v", err)
+			}
+			if !t.waitExit(exited) {
+				return fmt.Errorf("server process still running after SIGKILL, pid=%d", cmd.Process.Pid)
+			}
+		}
 	}
 
 	t.wg.Wait()
 
+	if stopped {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return t.exitErr
+	}
 	return nil
 }
 
-func (t *stdioClientTransport) startReceive(ctx context.Context) {
-	s := bufio.NewReader(t.reader)
+func (t *stdioClientTransport) waitExit(exited <-chan struct{}) bool {
+	timer := time.NewTimer(t.stopGracePeriod)
+	defer timer.Stop()
+
+	select {
+	case <-exited:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (t *stdioClientTransport) startReceive(ctx context.Context, f *framer, reader io.Reader) {
+	s := bufio.NewReader(reader)
 
 	for {
-		line, err := s.ReadBytes('\n')
+		line, err := f.readMessage(s)
 		if err != nil {
-			t.receiver.Interrupt(fmt.Errorf("stdout read error: %w", err))
-
-			if errors.Is(err, io.ErrClosedPipe) || // This error occurs during unit tests, suppressing it here
-				errors.Is(err, io.EOF) {
-				return
+			if errors.Is(err, pkg.ErrMessageTooLarge) {
+				t.logger.Errorf("skip received message: %v", err)
+				continue
+			}
+			// the exit of the process is reported by supervise
+			if !errors.Is(err, io.ErrClosedPipe) && // This error occurs during unit tests, suppressing it here
+				!errors.Is(err, io.EOF) && !errors.Is(err, os.ErrClosed) {
+				t.logger.Errorf("stdout read error: %+v", err)
 			}
-			t.logger.Errorf("stdout read error: %+v", err)
 			return
 		}
 
-		line = bytes.TrimRight(line, "\n")
-		// filter empty messages
-		// filter space messages and \t messages
-		if len(bytes.TrimFunc(line, func(r rune) bool { return r == ' ' || r == '\t' })) == 0 {
-			t.logger.Debugf("skipping empty message")
-			continue
-		}
-
 		select {
 		case <-ctx.Done():
 			return
@@ -165,33 +552,80 @@ func (t *stdioClientTransport) startReceive(ctx context.Context) {
 	}
 }
 
-func (t *stdioClientTransport) startReceiveErr(ctx context.Context) {
-	s := bufio.NewReader(t.errReader)
+func (t *stdioClientTransport) startReceiveErr(reader io.Reader) {
+	s := bufio.NewReader(reader)
 
+	var (
+		line []byte
+		// truncated is set once the line reached maxMessageSize, the rest of it is dropped
+		truncated bool
+	)
 	for {
-		line, err := s.ReadBytes('\n')
-		if err != nil {
-			if errors.Is(err, io.ErrClosedPipe) || // This error occurs during unit tests, suppressing it here
-				errors.Is(err, io.EOF) {
-				return
+		chunk, err := s.ReadSlice('\n')
+		if !truncated {
+			line = append(line, chunk...)
+			if t.maxMessageSize > 0 && int64(len(line)) > t.maxMessageSize {
+				line = line[:t.maxMessageSize]
+				truncated = true
 			}
-			t.logger.Errorf("client receive unexpected server error reading input: %v", err)
-			return
 		}
-
-		line = bytes.TrimRight(line, "\n")
-		// filter empty messages
-		// filter space messages and \t messages
-		if len(bytes.TrimFunc(line, func(r rune) bool { return r == ' ' || r == '\t' })) == 0 {
-			t.logger.Debugf("skipping empty message")
+		if errors.Is(err, bufio.ErrBufferFull) {
 			continue
 		}
-
-		select {
-		case <-ctx.Done():
+		if len(line) > 0 {
+			line = bytes.TrimRight(line, "\r\n")
+			if len(bytes.TrimSpace(line)) > 0 {
+				t.stderr.add(string(line))
+				t.stderrSink(line)
+			}
+		}
+		line, truncated = nil, false
+		if err != nil {
+			if !errors.Is(err, io.ErrClosedPipe) && // This error occurs during unit tests, suppressing it here
+				!errors.Is(err, io.EOF) && !errors.Is(err, os.ErrClosed) {
+				t.logger.Errorf("client receive unexpected server error reading input: %v", err)
+			}
 			return
-		default:
-			t.logger.Errorf("receive server error: %s", line)
 		}
 	}
 }
+
+// lineRing keeps the last lines written to it
+type lineRing struct {
+	mu    sync.Mutex
+	buf   []string
+	next  int
+	count int
+}
+
+func newLineRing(size int) *lineRing {
+	if size < 0 {
+		size = 0
+	}
+	return &lineRing{buf: make([]string, size)}
+}
+
+func (r *lineRing) add(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.buf) == 0 {
+		return
+	}
+	r.buf[r.next] = line
+	r.next = (r.next + 1) % len(r.buf)
+	if r.count < len(r.buf) {
+		r.count++
+	}
+}
+
+func (r *lineRing) lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lines := make([]string, 0, r.count)
+	for i := 0; i < r.count; i++ {
+		lines = append(lines, r.buf[(r.next-r.count+i+len(r.buf))%len(r.buf)])
+	}
+	return lines
+}
@@ -0,0 +1,32 @@
+//go:build !windows
+
+package transport
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts cmd in a process group of its own, so that its children can be signaled with it
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+func terminateProcess(p *os.Process, group bool) error {
+	return signalProcess(p, group, syscall.SIGTERM)
+}
+
+func killProcess(p *os.Process, group bool) error {
+	return signalProcess(p, group, syscall.SIGKILL)
+}
+
+func signalProcess(p *os.Process, group bool, sig syscall.Signal) error {
+	if group {
+		return syscall.Kill(-p.Pid, sig)
+	}
+	return p.Signal(sig)
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package transport
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is not supported on windows, only the process itself is stopped
+func setProcessGroup(*exec.Cmd) {}
+
+// terminateProcess kills the process, windows has no SIGTERM
+func terminateProcess(p *os.Process, _ bool) error {
+	return p.Kill()
+}
+
+func killProcess(p *os.Process, _ bool) error {
+	return p.Kill()
+}
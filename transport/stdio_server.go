@@ -2,12 +2,12 @@ package transport
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sync"
 
 	"github.com/ThinkInAIXYZ/go-mcp/pkg"
 )
@@ -20,11 +20,29 @@ func WithStdioServerOptionLogger(log pkg.Logger) StdioServerTransportOption {
 	}
 }
 
+// WithStdioServerOptionFraming sets how messages are delimited, FramingAuto by default.
+func WithStdioServerOptionFraming(framing Framing) StdioServerTransportOption {
+	return func(t *stdioServerTransport) {
+		t.framer.framing = int32(framing)
+	}
+}
+
+// WithStdioServerOptionMaxMessageSize limits the size of a received message, larger ones are skipped and answered
+// with a JSON-RPC error. 16 MiB by default, 0 for no limit.
+func WithStdioServerOptionMaxMessageSize(size int64) StdioServerTransportOption {
+	return func(t *stdioServerTransport) {
+		t.framer.maxMessageSize = size
+	}
+}
+
 type stdioServerTransport struct {
 	receiver serverReceiver
 	reader   io.ReadCloser
 	writer   io.Writer
 
+	framer  *framer
+	writeMu sync.Mutex
+
 	sessionManager sessionManager
 	sessionID      string
 
@@ -38,6 +56,7 @@ func NewStdioServerTransport(opts ...StdioServerTransportOption) ServerTransport
 	t := &stdioServerTransport{
 		reader: os.Stdin,
 		writer: os.Stdout,
+		framer: newFramer(FramingAuto, defaultMaxMessageSize),
 		logger: pkg.DefaultLogger,
 
 		receiveShutDone: make(chan struct{}),
@@ -62,7 +81,10 @@ func (t *stdioServerTransport) Run() error {
 }
 
 func (t *stdioServerTransport) Send(_ context.Context, _ string, msg Message) error {
-	if _, err := t.writer.Write(append(msg, mcpMessageDelimiter)); err != nil {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if err := t.framer.writeMessage(t.writer, msg); err != nil {
 		return fmt.Errorf("failed to write: %w", err)
 	}
 	return nil
@@ -97,15 +119,21 @@ func (t *stdioServerTransport) startReceive(ctx context.Context) {
 	s := bufio.NewReader(t.reader)
 
 	for {
-		line, err := s.ReadBytes('\n')
+		line, err := t.framer.readMessage(s)
 		if err != nil {
-			if errors.Is(err, io.ErrClosedPipe) || // This error occurs during unit tests, suppressing it here
-				errors.Is(err, io.EOF) {
-				return
+			if errors.Is(err, pkg.ErrMessageTooLarge) {
+				t.logger.Errorf("skip received message: %v", err)
+				if e := t.Send(context.Background(), t.sessionID, messageTooLargeResponse(err)); e != nil {
+					t.logger.Errorf("Failed to send message: %v", e)
+				}
+				continue
+			}
+			if !errors.Is(err, io.ErrClosedPipe) && // This error occurs during unit tests, suppressing it here
+				!errors.Is(err, io.EOF) && !errors.Is(err, os.ErrClosed) {
+				t.logger.Errorf("client receive unexpected error reading input: %v", err)
 			}
-			t.logger.Errorf("client receive unexpected error reading input: %v", err)
+			return
 		}
-		line = bytes.TrimRight(line, "\n")
 
 		select {
 		case <-ctx.Done():
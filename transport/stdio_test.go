@@ -1,15 +1,21 @@
 package transport
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
 )
 
 type mock struct {
@@ -89,3 +95,193 @@ func compileMockStdioServerTr(outputPath string) error {
 
 	return nil
 }
+
+func TestStdioClientRestart(t *testing.T) {
+	serverPath := compileSupervisedServer(t)
+
+	var exitErr *ProcessExitError
+	onExit := make(chan struct{}, 1)
+	clientT, err := NewStdioClientTransport(serverPath, []string{"-mode", "echo"},
+		WithStdioClientOptionRestartPolicy(RestartPolicy{MaxRestarts: 1, InitialBackoff: 10 * time.Millisecond}),
+		WithStdioClientOptionStderrSink(func([]byte) {}),
+		WithStdioClientOptionOnExit(func(e *ProcessExitError) {
+			exitErr = e
+			onExit <- struct{}{}
+		}))
+	if err != nil {
+		t.Fatalf("NewStdioClientTransport failed: %v", err)
+	}
+
+	received := make(chan string, 10)
+	interrupted := make(chan error, 10)
+	clientT.SetReceiver(NewClientReceiver(func(_ context.Context, msg []byte) error {
+		received <- string(msg)
+		return nil
+	}, func(err error) {
+		interrupted <- err
+	}))
+	if err = clientT.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer clientT.Close()
+
+	roundTrip := func(msg string) {
+		t.Helper()
+		if err := clientT.Send(context.Background(), Message(msg)); err != nil {
+			t.Fatalf("Send(%s) failed: %v", msg, err)
+		}
+		select {
+		case got := <-received:
+			if got != msg {
+				t.Fatalf("received %s, want %s", got, msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no echo of %s", msg)
+		}
+	}
+	roundTrip(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+
+	if err = clientT.Send(context.Background(), Message(`{"jsonrpc":"2.0","id":2,"method":"crash"}`)); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	select {
+	case err = <-interrupted:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("client not interrupted by the crash")
+	}
+	<-onExit
+	if !errors.As(err, &exitErr) || len(exitErr.Stderr) != 1 || exitErr.Stderr[0] != "panic: boom" {
+		t.Fatalf("Interrupt(%v), want a ProcessExitError with the stderr tail", err)
+	}
+	var status *exec.ExitError
+	if !errors.As(err, &status) || status.ExitCode() != 3 {
+		t.Fatalf("Interrupt(%v), want exit status 3", err)
+	}
+
+	// once restarted, messages are refused until the client initializes the new process
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err = clientT.Send(context.Background(), Message(`{"jsonrpc":"2.0","id":3,"method":"ping"}`))
+		if errors.Is(err, pkg.ErrSessionClosed) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Send after restart = %v, want pkg.ErrSessionClosed", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	roundTrip(`{"jsonrpc":"2.0","id":4,"method":"initialize"}`)
+	roundTrip(`{"jsonrpc":"2.0","id":5,"method":"ping"}`)
+}
+
+func TestStdioClientStopGracePeriod(t *testing.T) {
+	serverPath := compileSupervisedServer(t)
+
+	clientT, err := NewStdioClientTransport(serverPath, []string{"-mode", "stubborn"},
+		WithStdioClientOptionProcessGroup(),
+		WithStdioClientOptionStopGracePeriod(100*time.Millisecond),
+		WithStdioClientOptionStderrSink(func([]byte) {}))
+	if err != nil {
+		t.Fatalf("NewStdioClientTransport failed: %v", err)
+	}
+	clientT.SetReceiver(NewClientReceiver(func(context.Context, []byte) error { return nil }, func(error) {}))
+	if err = clientT.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	if err = clientT.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("Close took %v, want the process killed after the grace periods", elapsed)
+	}
+}
+
+func TestStdioClientCloseWithOrphanedOutput(t *testing.T) {
+	serverPath := compileSupervisedServer(t)
+
+	clientT, err := NewStdioClientTransport(serverPath, []string{"-mode", "orphan"},
+		WithStdioClientOptionStopGracePeriod(100*time.Millisecond),
+		WithStdioClientOptionStderrSink(func([]byte) {}))
+	if err != nil {
+		t.Fatalf("NewStdioClientTransport failed: %v", err)
+	}
+	clientT.SetReceiver(NewClientReceiver(func(context.Context, []byte) error { return nil }, func(error) {}))
+	if err = clientT.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// the process exits at the end of its stdin, its child keeps the pipes open
+	closed := make(chan error, 1)
+	go func() {
+		closed <- clientT.Close()
+	}()
+	select {
+	case err = <-closed:
+		if err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Close blocked on the pipes held by the child of the process")
+	}
+}
+
+func compileSupervisedServer(t *testing.T) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals are not supported on windows")
+	}
+	outputPath := filepath.Join(t.TempDir(), "mock_supervised_server")
+	cmd := exec.Command("go", "build", "-o", outputPath, "../testdata/mock_supervised_server.go")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("compilation failed: %v\nOutput: %s", err, output)
+	}
+	return outputPath
+}
+
+func TestRestartPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   RestartPolicy
+		restarts int
+		want     time.Duration
+	}{
+		{name: "first", policy: RestartPolicy{InitialBackoff: time.Second, MaxBackoff: time.Minute}, restarts: 0, want: time.Second},
+		{name: "doubled", policy: RestartPolicy{InitialBackoff: time.Second, MaxBackoff: time.Minute}, restarts: 3, want: 8 * time.Second},
+		{name: "capped", policy: RestartPolicy{InitialBackoff: time.Second, MaxBackoff: time.Minute}, restarts: 10, want: time.Minute},
+		{name: "default cap", policy: RestartPolicy{InitialBackoff: time.Second}, restarts: 10, want: defaultMaxRestartBackoff},
+		{name: "no overflow", policy: RestartPolicy{InitialBackoff: time.Second}, restarts: 1000, want: defaultMaxRestartBackoff},
+		{name: "zero initial", policy: RestartPolicy{}, restarts: 0, want: minRestartBackoff},
+		{name: "zero initial doubled", policy: RestartPolicy{}, restarts: 2, want: 4 * minRestartBackoff},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.backoff(tt.restarts); got != tt.want {
+				t.Errorf("backoff(%d) = %v, want %v", tt.restarts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStdioClientLongStderrLine(t *testing.T) {
+	var lines []string
+	transport := &stdioClientTransport{
+		logger:         pkg.DefaultLogger,
+		maxMessageSize: 8,
+		stderr:         newLineRing(defaultStderrRetention),
+		stderrSink: func(line []byte) {
+			lines = append(lines, string(line))
+		},
+	}
+
+	transport.startReceiveErr(strings.NewReader(strings.Repeat("x", 10000) + "\nshort\r\n"))
+
+	if len(lines) != 2 || lines[0] != "xxxxxxxx" || lines[1] != "short" {
+		t.Errorf("stderr lines = %q, want the long line truncated", lines)
+	}
+	if kept := transport.stderr.lines(); len(kept) != 2 || kept[0] != "xxxxxxxx" {
+		t.Errorf("kept stderr lines = %q, want the long line truncated", kept)
+	}
+}
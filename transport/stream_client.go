@@ -0,0 +1,138 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+)
+
+type StreamClientTransportOption func(*streamClientTransport)
+
+func WithStreamClientOptionLogger(log pkg.Logger) StreamClientTransportOption {
+	return func(t *streamClientTransport) {
+		t.logger = log
+	}
+}
+
+// WithStreamClientOptionFraming sets how messages are delimited, FramingAuto by default.
+func WithStreamClientOptionFraming(framing Framing) StreamClientTransportOption {
+	return func(t *streamClientTransport) {
+		t.framer.framing = int32(framing)
+	}
+}
+
+// WithStreamClientOptionMaxMessageSize limits the size of a received message, larger ones are skipped.
+// 16 MiB by default, 0 for no limit.
+func WithStreamClientOptionMaxMessageSize(size int64) StreamClientTransportOption {
+	return func(t *streamClientTransport) {
+		t.framer.maxMessageSize = size
+	}
+}
+
+type streamClientTransport struct {
+	receiver clientReceiver
+	rwc      io.ReadWriteCloser
+
+	framer  *framer
+	writeMu sync.Mutex
+
+	logger pkg.Logger
+
+	cancel          context.CancelFunc
+	receiveShutDone chan struct{}
+}
+
+// NewStreamClientTransport returns transport that talks to a server over rwc with the newline-delimited JSON
+// of the stdio transport, eg. a connection the host has already set up. Close closes rwc.
+func NewStreamClientTransport(rwc io.ReadWriteCloser, opts ...StreamClientTransportOption) ClientTransport {
+	t := &streamClientTransport{
+		rwc:             rwc,
+		framer:          newFramer(FramingAuto, defaultMaxMessageSize),
+		logger:          pkg.DefaultLogger,
+		receiveShutDone: make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func (t *streamClientTransport) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+
+	go func() {
+		defer pkg.Recover()
+		defer close(t.receiveShutDone)
+
+		t.startReceive(ctx)
+	}()
+
+	return nil
+}
+
+func (t *streamClientTransport) Send(_ context.Context, msg Message) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if err := t.framer.writeMessage(t.rwc, msg); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+	return nil
+}
+
+func (t *streamClientTransport) SetReceiver(receiver clientReceiver) {
+	t.receiver = receiver
+}
+
+func (t *streamClientTransport) Close() error {
+	if t.cancel != nil {
+		t.cancel()
+	}
+
+	if err := t.rwc.Close(); err != nil {
+		return fmt.Errorf("failed to close stream: %w", err)
+	}
+
+	// without Start there is no receiving to wait for
+	if t.cancel != nil {
+		<-t.receiveShutDone
+	}
+
+	return nil
+}
+
+func (t *streamClientTransport) startReceive(ctx context.Context) {
+	s := bufio.NewReader(t.rwc)
+
+	for {
+		line, err := t.framer.readMessage(s)
+		if err != nil {
+			if errors.Is(err, pkg.ErrMessageTooLarge) {
+				t.logger.Errorf("skip received message: %v", err)
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			t.receiver.Interrupt(fmt.Errorf("stream read error: %w", err))
+
+			if !errors.Is(err, io.ErrClosedPipe) && !errors.Is(err, io.EOF) {
+				t.logger.Errorf("stream read error: %+v", err)
+			}
+			return
+		}
+
+		if err = t.receiver.Receive(ctx, line); err != nil {
+			t.logger.Errorf("receiver failed: %v", err)
+		}
+	}
+}
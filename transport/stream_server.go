@@ -0,0 +1,172 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+)
+
+type StreamServerTransportOption func(*streamServerTransport)
+
+func WithStreamServerOptionLogger(log pkg.Logger) StreamServerTransportOption {
+	return func(t *streamServerTransport) {
+		t.logger = log
+	}
+}
+
+// WithStreamServerOptionFraming sets how messages are delimited, FramingAuto by default.
+func WithStreamServerOptionFraming(framing Framing) StreamServerTransportOption {
+	return func(t *streamServerTransport) {
+		t.framer.framing = int32(framing)
+	}
+}
+
+// WithStreamServerOptionMaxMessageSize limits the size of a received message, larger ones are skipped and answered
+// with a JSON-RPC error. 16 MiB by default, 0 for no limit.
+func WithStreamServerOptionMaxMessageSize(size int64) StreamServerTransportOption {
+	return func(t *streamServerTransport) {
+		t.framer.maxMessageSize = size
+	}
+}
+
+type streamServerTransport struct {
+	receiver serverReceiver
+	reader   io.Reader
+	writer   io.Writer
+
+	framer  *framer
+	writeMu sync.Mutex
+
+	sessionManager sessionManager
+	sessionID      string
+
+	logger pkg.Logger
+
+	cancel          context.CancelFunc
+	receiveShutDone chan struct{}
+}
+
+// NewStreamServerTransport returns transport that serves a single session over reader and writer
+// with the newline-delimited JSON of the stdio transport, eg. over pipes set up by the host.
+// Shutdown closes reader if it is an io.Closer.
+func NewStreamServerTransport(reader io.Reader, writer io.Writer, opts ...StreamServerTransportOption) ServerTransport {
+	t := &streamServerTransport{
+		reader: reader,
+		writer: writer,
+		framer: newFramer(FramingAuto, defaultMaxMessageSize),
+		logger: pkg.DefaultLogger,
+
+		receiveShutDone: make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func (t *streamServerTransport) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+
+	t.sessionID = t.sessionManager.CreateSession(context.Background())
+
+	t.startReceive(ctx)
+
+	close(t.receiveShutDone)
+	return nil
+}
+
+func (t *streamServerTransport) Send(_ context.Context, _ string, msg Message) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if err := t.framer.writeMessage(t.writer, msg); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+	return nil
+}
+
+func (t *streamServerTransport) SetReceiver(receiver serverReceiver) {
+	t.receiver = receiver
+}
+
+func (t *streamServerTransport) SetSessionManager(m sessionManager) {
+	t.sessionManager = m
+}
+
+func (t *streamServerTransport) Shutdown(userCtx context.Context, serverCtx context.Context) error {
+	if t.cancel != nil {
+		t.cancel()
+	}
+
+	if closer, ok := t.reader.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case <-t.receiveShutDone:
+		return nil
+	case <-serverCtx.Done():
+		return nil
+	case <-userCtx.Done():
+		return userCtx.Err()
+	}
+}
+
+func (t *streamServerTransport) startReceive(ctx context.Context) {
+	s := bufio.NewReader(t.reader)
+
+	for {
+		line, err := t.framer.readMessage(s)
+		if err != nil {
+			if errors.Is(err, pkg.ErrMessageTooLarge) {
+				t.logger.Errorf("skip received message: %v", err)
+				if e := t.Send(context.Background(), t.sessionID, messageTooLargeResponse(err)); e != nil {
+					t.logger.Errorf("Failed to send message: %v", e)
+				}
+				continue
+			}
+			if !errors.Is(err, io.ErrClosedPipe) && !errors.Is(err, io.EOF) {
+				t.logger.Errorf("stream read error: %v", err)
+			}
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			t.receive(ctx, line)
+		}
+	}
+}
+
+func (t *streamServerTransport) receive(ctx context.Context, line []byte) {
+	outputMsgCh, err := t.receiver.Receive(ctx, t.sessionID, line)
+	if err != nil {
+		t.logger.Errorf("receiver failed: %v", err)
+		return
+	}
+
+	if outputMsgCh == nil {
+		return
+	}
+
+	go func() {
+		defer pkg.Recover()
+
+		for msg := range outputMsgCh {
+			if e := t.Send(context.Background(), t.sessionID, msg); e != nil {
+				t.logger.Errorf("Failed to send message: %v", e)
+			}
+		}
+	}()
+}
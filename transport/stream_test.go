@@ -0,0 +1,122 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+)
+
+func TestStreamTransport(t *testing.T) {
+	clientSide, serverSide := net.Pipe()
+
+	testTransport(t, NewStreamClientTransport(clientSide), NewStreamServerTransport(serverSide, serverSide))
+}
+
+func TestStreamClientCloseWithoutStart(t *testing.T) {
+	clientSide, serverSide := net.Pipe()
+	defer serverSide.Close()
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- NewStreamClientTransport(clientSide).Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("Close() failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Close() without Start did not return")
+	}
+}
+
+func TestInMemoryPair(t *testing.T) {
+	client, server := NewInMemoryPair()
+
+	testTransport(t, client, server)
+}
+
+func TestInMemoryPairServerShutdown(t *testing.T) {
+	client, server := NewInMemoryPair()
+
+	server.SetSessionManager(newMockSessionManager())
+	server.SetReceiver(ServerReceiverF(func(context.Context, string, []byte) (<-chan []byte, error) {
+		return nil, nil
+	}))
+	go func() {
+		_ = server.Run()
+	}()
+
+	interrupted := make(chan error, 1)
+	client.SetReceiver(NewClientReceiver(func(context.Context, []byte) error {
+		return nil
+	}, func(err error) {
+		interrupted <- err
+	}))
+	if err := client.Start(); err != nil {
+		t.Fatalf("client.Start() failed: %v", err)
+	}
+	defer client.Close()
+
+	serverCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := server.Shutdown(context.Background(), serverCtx); err != nil {
+		t.Fatalf("server.Shutdown() failed: %v", err)
+	}
+
+	select {
+	case err := <-interrupted:
+		if !errors.Is(err, io.ErrClosedPipe) {
+			t.Fatalf("Interrupt(%v), want io.ErrClosedPipe", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("client not interrupted by the server shutdown")
+	}
+	if err := client.Send(context.Background(), Message("{}")); !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("client.Send() after shutdown = %v, want io.ErrClosedPipe", err)
+	}
+}
+
+func TestStreamServerMessageTooLarge(t *testing.T) {
+	clientSide, serverSide := net.Pipe()
+	defer clientSide.Close()
+
+	server := NewStreamServerTransport(serverSide, serverSide, WithStreamServerOptionMaxMessageSize(16))
+	server.SetSessionManager(newMockSessionManager())
+	received := make(chan string, 1)
+	server.SetReceiver(ServerReceiverF(func(_ context.Context, _ string, msg []byte) (<-chan []byte, error) {
+		received <- string(msg)
+		return nil, nil
+	}))
+	go func() {
+		_ = server.Run()
+	}()
+
+	go func() {
+		_, _ = clientSide.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"ping"}` + "\n" + `{"id":2}` + "\n"))
+	}()
+
+	// the oversized message is answered with an error, the next one goes through
+	line, err := bufio.NewReader(clientSide).ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	if !strings.Contains(string(line), `"id":null`) || !strings.Contains(string(line), pkg.ErrMessageTooLarge.Error()) {
+		t.Errorf("response = %s, want a message too large error", line)
+	}
+	select {
+	case msg := <-received:
+		if msg != `{"id":2}` {
+			t.Errorf("received %s, want {\"id\":2}", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("message after the oversized one not received")
+	}
+}
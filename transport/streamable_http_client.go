@@ -1,9 +1,10 @@
 package transport
 
 import (
-	"bufio"
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net/http"
@@ -39,6 +40,29 @@ func WithStreamableHTTPClientOptionLogger(log pkg.Logger) StreamableHTTPClientTr
 	}
 }
 
+// WithStreamableHTTPClientOptionTLSConfig sets the TLS configuration of the connections, completed by the other TLS options.
+func WithStreamableHTTPClientOptionTLSConfig(config *tls.Config) StreamableHTTPClientTransportOption {
+	return func(t *streamableHTTPClientTransport) {
+		t.tls.config = config
+	}
+}
+
+// WithStreamableHTTPClientOptionClientCertificate authenticates to servers requiring mutual TLS with the certificate and key
+// of the given files, loaded again whenever they change.
+func WithStreamableHTTPClientOptionClientCertificate(certFile, keyFile string) StreamableHTTPClientTransportOption {
+	return func(t *streamableHTTPClientTransport) {
+		t.tls.certFile = certFile
+		t.tls.keyFile = keyFile
+	}
+}
+
+// WithStreamableHTTPClientOptionRootCAs sets the authorities server certificates are verified against, the system ones by default.
+func WithStreamableHTTPClientOptionRootCAs(pool *x509.CertPool) StreamableHTTPClientTransportOption {
+	return func(t *streamableHTTPClientTransport) {
+		t.tls.rootCAs = pool
+	}
+}
+
 type streamableHTTPClientTransport struct {
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -51,6 +75,7 @@ type streamableHTTPClientTransport struct {
 	logger         pkg.Logger
 	receiveTimeout time.Duration
 	client         *http.Client
+	tls            clientTLS
 
 	sseInFlyConnect sync.WaitGroup
 }
@@ -77,6 +102,11 @@ func NewStreamableHTTPClientTransport(serverURL string, opts ...StreamableHTTPCl
 		opt(t)
 	}
 
+	if t.client, err = t.tls.httpClient(t.client); err != nil {
+		cancel()
+		return nil, err
+	}
+
 	return t, nil
 }
 
@@ -218,18 +248,12 @@ func (t *streamableHTTPClientTransport) startSSEStream() {
 func (t *streamableHTTPClientTransport) handleSSEStream(reader io.ReadCloser) {
 	defer reader.Close()
 
-	br := bufio.NewReader(reader)
-	var data string
-
+	decoder := newSSEDecoder(reader)
 	for {
-		line, err := br.ReadString('\n')
+		event, err := decoder.next()
 		if err != nil {
 			if err == io.EOF {
-				// Process any pending event before exit
-				if data != "" {
-					t.processSSEEvent(data)
-				}
-				break
+				return
 			}
 			select {
 			case <-t.ctx.Done():
@@ -239,21 +263,7 @@ func (t *streamableHTTPClientTransport) handleSSEStream(reader io.ReadCloser) {
 				return
 			}
 		}
-
-		line = strings.TrimRight(line, "\r\n")
-
-		if line == "" {
-			// Empty line means end of event
-			if data != "" {
-				t.processSSEEvent(data)
-				_, data = "", ""
-			}
-			continue
-		}
-
-		if strings.HasPrefix(line, "data:") {
-			data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
-		}
+		t.processSSEEvent(string(event.data))
 	}
 }
 
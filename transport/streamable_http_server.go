@@ -2,15 +2,18 @@ package transport
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/tidwall/gjson"
+
 	"github.com/ThinkInAIXYZ/go-mcp/pkg"
 	"github.com/ThinkInAIXYZ/go-mcp/protocol"
 )
@@ -48,6 +51,63 @@ func WithStreamableHTTPServerTransportOptionStateMode(mode StateMode) Streamable
 	}
 }
 
+// WithStreamableHTTPServerTransportOptionMaxMessageSize limits the size of a posted message, larger ones are refused
+// with 413 Request Entity Too Large. 16 MiB by default, 0 for no limit.
+func WithStreamableHTTPServerTransportOptionMaxMessageSize(size int64) StreamableHTTPServerTransportOption {
+	return func(t *streamableHTTPServerTransport) {
+		t.maxMessageSize = size
+	}
+}
+
+// WithStreamableHTTPServerTransportOptionAllowedOrigins sets the origins, as "https://app.example.com", browsers may call the server from
+// besides its own, "*" for any. Loopback origins are allowed too when the server is bound to a loopback address.
+func WithStreamableHTTPServerTransportOptionAllowedOrigins(origins ...string) StreamableHTTPServerTransportOption {
+	return func(t *streamableHTTPServerTransport) {
+		t.originGuard.allowedOrigins = origins
+	}
+}
+
+// WithStreamableHTTPServerTransportOptionAllowedHosts sets the Host values, with or without port, the server answers to. By default
+// any Host is accepted, but a loopback name only when the server is bound to a loopback address.
+func WithStreamableHTTPServerTransportOptionAllowedHosts(hosts ...string) StreamableHTTPServerTransportOption {
+	return func(t *streamableHTTPServerTransport) {
+		t.originGuard.allowedHosts = hosts
+	}
+}
+
+// WithStreamableHTTPServerTransportOptionTLS serves over TLS with the certificate and key of the given files,
+// loaded again whenever they change so that renewed certificates are used without a restart.
+func WithStreamableHTTPServerTransportOptionTLS(certFile, keyFile string) StreamableHTTPServerTransportOption {
+	return func(t *streamableHTTPServerTransport) {
+		t.tls.certFile = certFile
+		t.tls.keyFile = keyFile
+	}
+}
+
+// WithStreamableHTTPServerTransportOptionTLSConfig serves over TLS with config, completed by the other TLS options.
+func WithStreamableHTTPServerTransportOptionTLSConfig(config *tls.Config) StreamableHTTPServerTransportOption {
+	return func(t *streamableHTTPServerTransport) {
+		t.tls.config = config
+	}
+}
+
+// WithStreamableHTTPServerTransportOptionClientCAs requires clients to authenticate with a certificate issued by one of pool,
+// handlers get it with GetClientCertificateFromCtx.
+func WithStreamableHTTPServerTransportOptionClientCAs(pool *x509.CertPool) StreamableHTTPServerTransportOption {
+	return func(t *streamableHTTPServerTransport) {
+		t.tls.clientCAs = pool
+	}
+}
+
+// WithStreamableHTTPServerTransportOptionJSONResponse answers a request with a single application/json body
+// when its response is the only message, streaming only when notifications or requests, of progress or sampling
+// for example, precede it. Clients not accepting text/event-stream get JSON bodies regardless.
+func WithStreamableHTTPServerTransportOptionJSONResponse() StreamableHTTPServerTransportOption {
+	return func(t *streamableHTTPServerTransport) {
+		t.jsonResponse = true
+	}
+}
+
 type StreamableHTTPServerTransportAndHandlerOption func(*streamableHTTPServerTransport)
 
 func WithStreamableHTTPServerTransportAndHandlerOptionLogger(logger pkg.Logger) StreamableHTTPServerTransportAndHandlerOption {
@@ -62,6 +122,30 @@ func WithStreamableHTTPServerTransportAndHandlerOptionStateMode(mode StateMode)
 	}
 }
 
+func WithStreamableHTTPServerTransportAndHandlerOptionMaxMessageSize(size int64) StreamableHTTPServerTransportAndHandlerOption {
+	return func(t *streamableHTTPServerTransport) {
+		t.maxMessageSize = size
+	}
+}
+
+func WithStreamableHTTPServerTransportAndHandlerOptionAllowedOrigins(origins ...string) StreamableHTTPServerTransportAndHandlerOption {
+	return func(t *streamableHTTPServerTransport) {
+		t.originGuard.allowedOrigins = origins
+	}
+}
+
+func WithStreamableHTTPServerTransportAndHandlerOptionAllowedHosts(hosts ...string) StreamableHTTPServerTransportAndHandlerOption {
+	return func(t *streamableHTTPServerTransport) {
+		t.originGuard.allowedHosts = hosts
+	}
+}
+
+func WithStreamableHTTPServerTransportAndHandlerOptionJSONResponse() StreamableHTTPServerTransportAndHandlerOption {
+	return func(t *streamableHTTPServerTransport) {
+		t.jsonResponse = true
+	}
+}
+
 type streamableHTTPServerTransport struct {
 	// ctx is the context that controls the lifecycle of the server
 	ctx    context.Context
@@ -78,8 +162,12 @@ type streamableHTTPServerTransport struct {
 	sessionManager sessionManager
 
 	// options
-	logger      pkg.Logger
-	mcpEndpoint string // The single MCP endpoint path
+	logger         pkg.Logger
+	mcpEndpoint    string // The single MCP endpoint path
+	maxMessageSize int64
+	originGuard    originGuard
+	tls            serverTLS
+	jsonResponse   bool
 }
 
 type StreamableHTTPHandler struct {
@@ -106,10 +194,11 @@ func NewStreamableHTTPServerTransportAndHandler(
 	ctx, cancel := context.WithCancel(context.Background())
 
 	t := &streamableHTTPServerTransport{
-		ctx:       ctx,
-		cancel:    cancel,
-		stateMode: Stateless,
-		logger:    pkg.DefaultLogger,
+		ctx:            ctx,
+		cancel:         cancel,
+		stateMode:      Stateless,
+		logger:         pkg.DefaultLogger,
+		maxMessageSize: defaultMaxMessageSize,
 	}
 
 	for _, opt := range opts {
@@ -123,11 +212,12 @@ func NewStreamableHTTPServerTransport(addr string, opts ...StreamableHTTPServerT
 	ctx, cancel := context.WithCancel(context.Background())
 
 	t := &streamableHTTPServerTransport{
-		ctx:         ctx,
-		cancel:      cancel,
-		stateMode:   Stateless,
-		logger:      pkg.DefaultLogger,
-		mcpEndpoint: "/mcp", // Default MCP endpoint
+		ctx:            ctx,
+		cancel:         cancel,
+		stateMode:      Stateless,
+		logger:         pkg.DefaultLogger,
+		mcpEndpoint:    "/mcp", // Default MCP endpoint
+		maxMessageSize: defaultMaxMessageSize,
 	}
 
 	for _, opt := range opts {
@@ -152,9 +242,9 @@ func (t *streamableHTTPServerTransport) Run() error {
 		return nil
 	}
 
-	fmt.Printf("starting mcp server at http://%s%s\n", t.httpSvr.Addr, t.mcpEndpoint)
+	fmt.Printf("starting mcp server at %s://%s%s\n", t.tls.scheme("http"), t.httpSvr.Addr, t.mcpEndpoint)
 
-	if err := t.httpSvr.ListenAndServe(); err != nil {
+	if err := t.tls.listenAndServe(t.httpSvr); err != nil {
 		return fmt.Errorf("failed to start HTTP server: %w", err)
 	}
 	return nil
@@ -185,7 +275,14 @@ func (t *streamableHTTPServerTransport) handleMCPEndpoint(w http.ResponseWriter,
 		t.writeError(w, http.StatusInternalServerError, "Internal server error")
 	})
 
+	if err := t.originGuard.check(w, r); err != nil {
+		t.writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
 	switch r.Method {
+	case http.MethodOptions:
+		t.originGuard.preflight(w, "GET, POST, DELETE, OPTIONS")
 	case http.MethodPost:
 		t.handlePost(w, r)
 	case http.MethodGet:
@@ -204,15 +301,24 @@ func (t *streamableHTTPServerTransport) handlePost(w http.ResponseWriter, r *htt
 		t.writeError(w, http.StatusBadRequest, "Missing Accept header")
 		return
 	}
+	acceptJSON, acceptStream := acceptsMediaType(accept, "application/json"), acceptsMediaType(accept, "text/event-stream")
+	if !acceptJSON && !acceptStream {
+		t.writeError(w, http.StatusNotAcceptable, "Must accept application/json or text/event-stream")
+		return
+	}
 
 	// Read and process the message
-	bs, err := io.ReadAll(r.Body)
+	bs, err := readBody(r, t.maxMessageSize)
 	if err != nil {
+		if errors.Is(err, pkg.ErrMessageTooLarge) {
+			t.writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
 		t.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
 		return
 	}
 
-	ctx := r.Context()
+	ctx := setClientCertificateToCtx(r.Context(), r)
 
 	// For InitializeRequest HTTP response
 	if t.stateMode == Stateful {
@@ -235,6 +341,28 @@ func (t *streamableHTTPServerTransport) handlePost(w http.ResponseWriter, r *htt
 		return
 	}
 
+	var pending []byte
+	if acceptJSON && (t.jsonResponse || !acceptStream) {
+		msg, ok := <-outputMsgCh
+		if !acceptStream {
+			// the messages preceding the response can only go through the stream of the session
+			for ok && !isJSONRPCResponse(msg) {
+				t.forwardToSession(ctx, r.Header.Get(sessionIDHeader), msg)
+				msg, ok = <-outputMsgCh
+			}
+		}
+		if !ok {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		if isJSONRPCResponse(msg) {
+			t.writeJSONResponse(ctx, w, msg)
+			return
+		}
+		// progress or server requests are in flight, the response follows them on a stream
+		pending = msg
+	}
+
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		t.writeError(w, http.StatusInternalServerError, "Streaming not supported")
@@ -246,12 +374,10 @@ func (t *streamableHTTPServerTransport) handlePost(w http.ResponseWriter, r *htt
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 
-	if protocol.IsInitializedRequest(bs) { // 判断是否是init请求
+	if pending == nil && protocol.IsInitializedRequest(bs) { // 判断是否是init请求
 		msg := <-outputMsgCh
-		if t.stateMode == Stateful {
-			w.Header().Set(sessionIDHeader, ctx.Value(SessionIDForReturnKey{}).(*SessionIDForReturn).SessionID)
-		}
-		if _, err = fmt.Fprintf(w, "data: %s\n\n", msg); err != nil {
+		t.setSessionIDHeader(ctx, w)
+		if err = writeSSEEvent(w, &sseEvent{data: msg}); err != nil {
 			t.logger.Errorf("Failed to write message: %v", err)
 		}
 		flusher.Flush()
@@ -272,7 +398,7 @@ func (t *streamableHTTPServerTransport) handlePost(w http.ResponseWriter, r *htt
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				if _, e := fmt.Fprintf(w, " : heartbeat\n\n"); e != nil {
+				if e := writeSSEComment(w, "heartbeat"); e != nil {
 					t.logger.Errorf("Failed to write heartbeat: %v", e)
 					continue
 				}
@@ -281,8 +407,15 @@ func (t *streamableHTTPServerTransport) handlePost(w http.ResponseWriter, r *htt
 		}
 	}()
 
+	if pending != nil {
+		if err = writeSSEEvent(w, &sseEvent{data: pending}); err != nil {
+			t.logger.Errorf("Failed to write message: %v", err)
+		}
+		flusher.Flush()
+	}
+
 	for msg := range outputMsgCh {
-		if _, err = fmt.Fprintf(w, "data: %s\n\n", msg); err != nil {
+		if err = writeSSEEvent(w, &sseEvent{data: msg}); err != nil {
 			t.logger.Errorf("Failed to write message: %v", err)
 			continue
 		}
@@ -290,6 +423,53 @@ func (t *streamableHTTPServerTransport) handlePost(w http.ResponseWriter, r *htt
 	}
 }
 
+// setSessionIDHeader returns the session created by an initialize request, it must precede the response
+func (t *streamableHTTPServerTransport) setSessionIDHeader(ctx context.Context, w http.ResponseWriter) {
+	if t.stateMode != Stateful {
+		return
+	}
+	if sessionID := ctx.Value(SessionIDForReturnKey{}).(*SessionIDForReturn).SessionID; sessionID != "" {
+		w.Header().Set(sessionIDHeader, sessionID)
+	}
+}
+
+func (t *streamableHTTPServerTransport) writeJSONResponse(ctx context.Context, w http.ResponseWriter, msg []byte) {
+	t.setSessionIDHeader(ctx, w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(msg); err != nil {
+		t.logger.Errorf("Failed to write message: %v", err)
+	}
+}
+
+func (t *streamableHTTPServerTransport) forwardToSession(ctx context.Context, sessionID string, msg []byte) {
+	if sessionID == "" {
+		t.logger.Warnf("drop message of a client not accepting streams: %s", msg)
+		return
+	}
+	if err := t.Send(ctx, sessionID, msg); err != nil {
+		t.logger.Errorf("Failed to send message: %v", err)
+	}
+}
+
+// isJSONRPCResponse reports whether msg is a response, rather than a request or a notification
+func isJSONRPCResponse(msg []byte) bool {
+	return !gjson.GetBytes(msg, "method").Exists() && gjson.GetBytes(msg, "id").Exists()
+}
+
+// acceptsMediaType reports whether the Accept header value accept includes mediaType
+func acceptsMediaType(accept, mediaType string) bool {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	for _, part := range strings.Split(accept, ",") {
+		rangeType, _, _ := strings.Cut(part, ";")
+		switch strings.ToLower(strings.TrimSpace(rangeType)) {
+		case mediaType, typ + "/*", "*/*":
+			return true
+		}
+	}
+	return false
+}
+
 func (t *streamableHTTPServerTransport) handleGet(w http.ResponseWriter, r *http.Request) {
 	defer pkg.RecoverWithFunc(func(_ any) {
 		t.writeError(w, http.StatusInternalServerError, "Internal server error")
@@ -342,7 +522,7 @@ func (t *streamableHTTPServerTransport) handleGet(w http.ResponseWriter, r *http
 
 		t.logger.Debugf("Sending message: %s", string(msg))
 
-		if _, err = fmt.Fprintf(w, "data: %s\n\n", msg); err != nil {
+		if err = writeSSEEvent(w, &sseEvent{data: msg}); err != nil {
 			t.logger.Errorf("Failed to write message: %v", err)
 			continue
 		}
@@ -1,7 +1,11 @@
 package transport
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -29,3 +33,252 @@ func TestStreamableHTTP(t *testing.T) {
 
 	testTransport(t, client, svr)
 }
+
+func TestStreamableHTTPMaxMessageSize(t *testing.T) {
+	svr, handler, err := NewStreamableHTTPServerTransportAndHandler(WithStreamableHTTPServerTransportAndHandlerOptionMaxMessageSize(16))
+	if err != nil {
+		t.Fatalf("NewStreamableHTTPServerTransportAndHandler failed: %v", err)
+	}
+	svr.SetSessionManager(newMockSessionManager())
+	svr.SetReceiver(ServerReceiverF(func(context.Context, string, []byte) (<-chan []byte, error) {
+		t.Errorf("oversized message received")
+		return nil, nil
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.HandleMCP().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestStreamableHTTPOrigin(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []StreamableHTTPServerTransportAndHandlerOption
+		host       string
+		origin     string
+		wantStatus int
+	}{
+		{name: "no_origin", wantStatus: http.StatusNoContent},
+		{name: "rebound_host", host: "attacker.example", wantStatus: http.StatusForbidden},
+		{name: "cross_origin", origin: "http://attacker.example", wantStatus: http.StatusForbidden},
+		{name: "null_origin", origin: "null", wantStatus: http.StatusForbidden},
+		{name: "loopback_origin", origin: "http://localhost:5173", wantStatus: http.StatusNoContent},
+		{
+			name:       "allowed_origin",
+			opts:       []StreamableHTTPServerTransportAndHandlerOption{WithStreamableHTTPServerTransportAndHandlerOptionAllowedOrigins("https://app.example")},
+			origin:     "https://app.example",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "allowed_host",
+			opts:       []StreamableHTTPServerTransportAndHandlerOption{WithStreamableHTTPServerTransportAndHandlerOptionAllowedHosts("mcp.example")},
+			host:       "mcp.example:8080",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "not_allowed_host",
+			opts:       []StreamableHTTPServerTransportAndHandlerOption{WithStreamableHTTPServerTransportAndHandlerOptionAllowedHosts("mcp.example")},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, handler, err := NewStreamableHTTPServerTransportAndHandler(tt.opts...)
+			if err != nil {
+				t.Fatalf("NewStreamableHTTPServerTransportAndHandler failed: %v", err)
+			}
+			// the test server listens on a loopback address
+			svr := httptest.NewServer(handler.HandleMCP())
+			defer svr.Close()
+
+			req, err := http.NewRequest(http.MethodOptions, svr.URL, nil)
+			if err != nil {
+				t.Fatalf("NewRequest failed: %v", err)
+			}
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Do failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.origin == "" || resp.StatusCode != http.StatusNoContent {
+				return
+			}
+			if got := resp.Header.Get("Access-Control-Allow-Origin"); got != tt.origin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.origin)
+			}
+			if !strings.Contains(resp.Header.Get("Access-Control-Allow-Headers"), sessionIDHeader) ||
+				!strings.Contains(resp.Header.Get("Access-Control-Expose-Headers"), sessionIDHeader) {
+				t.Errorf("%s not allowed and exposed: %v", sessionIDHeader, resp.Header)
+			}
+		})
+	}
+}
+
+func TestStreamableHTTPJSONResponse(t *testing.T) {
+	const (
+		request  = `{"jsonrpc":"2.0","id":1,"method":"tools/call"}`
+		progress = `{"jsonrpc":"2.0","method":"notifications/progress","params":{"progressToken":1,"progress":1}}`
+		response = `{"jsonrpc":"2.0","id":1,"result":{}}`
+	)
+	tests := []struct {
+		name            string
+		opts            []StreamableHTTPServerTransportAndHandlerOption
+		accept          string
+		messages        []string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "json_response",
+			opts:            []StreamableHTTPServerTransportAndHandlerOption{WithStreamableHTTPServerTransportAndHandlerOptionJSONResponse()},
+			accept:          "application/json, text/event-stream",
+			messages:        []string{response},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        response,
+		},
+		{
+			name:            "json_response_with_progress",
+			opts:            []StreamableHTTPServerTransportAndHandlerOption{WithStreamableHTTPServerTransportAndHandlerOptionJSONResponse()},
+			accept:          "application/json, text/event-stream",
+			messages:        []string{progress, response},
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/event-stream",
+			wantBody:        "data: " + progress + "\n\ndata: " + response + "\n\n",
+		},
+		{
+			name:            "stream_by_default",
+			accept:          "application/json, text/event-stream",
+			messages:        []string{response},
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/event-stream",
+			wantBody:        "data: " + response + "\n\n",
+		},
+		{
+			name:            "client_accepts_json_only",
+			accept:          "application/json",
+			messages:        []string{progress, response},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        response,
+		},
+		{
+			name:       "not_acceptable",
+			accept:     "text/html",
+			messages:   []string{response},
+			wantStatus: http.StatusNotAcceptable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svr, handler, err := NewStreamableHTTPServerTransportAndHandler(tt.opts...)
+			if err != nil {
+				t.Fatalf("NewStreamableHTTPServerTransportAndHandler failed: %v", err)
+			}
+			svr.SetSessionManager(newMockSessionManager())
+			svr.SetReceiver(ServerReceiverF(func(context.Context, string, []byte) (<-chan []byte, error) {
+				msgCh := make(chan []byte, len(tt.messages))
+				for _, msg := range tt.messages {
+					msgCh <- []byte(msg)
+				}
+				close(msgCh)
+				return msgCh, nil
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(request))
+			req.Header.Set("Accept", tt.accept)
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			handler.HandleMCP().ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if got := rec.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestStreamableHTTPInitializeSessionID(t *testing.T) {
+	const (
+		request  = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+		response = `{"jsonrpc":"2.0","id":1,"result":{}}`
+	)
+	tests := []struct {
+		name            string
+		opts            []StreamableHTTPServerTransportAndHandlerOption
+		wantContentType string
+	}{
+		{
+			name:            "json_response",
+			opts:            []StreamableHTTPServerTransportAndHandlerOption{WithStreamableHTTPServerTransportAndHandlerOptionJSONResponse()},
+			wantContentType: "application/json",
+		},
+		{
+			name:            "stream",
+			wantContentType: "text/event-stream",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]StreamableHTTPServerTransportAndHandlerOption{
+				WithStreamableHTTPServerTransportAndHandlerOptionStateMode(Stateful),
+			}, tt.opts...)
+			svr, handler, err := NewStreamableHTTPServerTransportAndHandler(opts...)
+			if err != nil {
+				t.Fatalf("NewStreamableHTTPServerTransportAndHandler failed: %v", err)
+			}
+			svr.SetSessionManager(newMockSessionManager())
+			svr.SetReceiver(ServerReceiverF(func(ctx context.Context, _ string, _ []byte) (<-chan []byte, error) {
+				// the server creates the session while handling the request
+				ctx.Value(SessionIDForReturnKey{}).(*SessionIDForReturn).SessionID = "session-1"
+				msgCh := make(chan []byte, 1)
+				msgCh <- []byte(response)
+				close(msgCh)
+				return msgCh, nil
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(request))
+			req.Header.Set("Accept", "application/json, text/event-stream")
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			handler.HandleMCP().ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if got := rec.Header().Get(sessionIDHeader); got != "session-1" {
+				t.Errorf("%s = %q, want session-1", sessionIDHeader, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader loads a certificate and its key from files, again whenever one of them changes,
+// so that renewed certificates are used without a restart.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.certificate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// certificate returns the current certificate, the previous one when the files can't be loaded
+// after a change, half written by a renewal for example.
+func (r *certReloader) certificate() (*tls.Certificate, error) {
+	modTime, err := r.lastModified()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err == nil && (r.cert == nil || !modTime.Equal(r.modTime)) {
+		var cert tls.Certificate
+		if cert, err = tls.LoadX509KeyPair(r.certFile, r.keyFile); err == nil {
+			r.cert, r.modTime = &cert, modTime
+		}
+	}
+	if r.cert == nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	return r.cert, nil
+}
+
+func (r *certReloader) lastModified() (time.Time, error) {
+	var modTime time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	return modTime, nil
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.certificate()
+}
+
+func (r *certReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.certificate()
+}
+
+// serverTLS holds the TLS options of the HTTP server transports, which serve plain HTTP without any.
+type serverTLS struct {
+	certFile  string
+	keyFile   string
+	config    *tls.Config
+	clientCAs *x509.CertPool
+}
+
+func (s *serverTLS) enabled() bool {
+	return s.certFile != "" || s.config != nil || s.clientCAs != nil
+}
+
+func (s *serverTLS) scheme(plain string) string {
+	if s.enabled() {
+		return plain + "s"
+	}
+	return plain
+}
+
+func (s *serverTLS) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if s.config != nil {
+		config = s.config.Clone()
+	}
+	if s.certFile != "" {
+		reloader, err := newCertReloader(s.certFile, s.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.GetCertificate = reloader.GetCertificate
+	}
+	if s.clientCAs != nil {
+		config.ClientCAs = s.clientCAs
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if len(config.Certificates) == 0 && config.GetCertificate == nil && config.GetConfigForClient == nil {
+		return nil, errors.New("TLS needs a server certificate")
+	}
+	return config, nil
+}
+
+// listenAndServe serves svr over TLS when enabled, over plain HTTP otherwise
+func (s *serverTLS) listenAndServe(svr *http.Server) error {
+	if !s.enabled() {
+		return svr.ListenAndServe()
+	}
+	config, err := s.tlsConfig()
+	if err != nil {
+		return err
+	}
+	svr.TLSConfig = config
+	return svr.ListenAndServeTLS("", "")
+}
+
+// clientTLS holds the TLS options of the HTTP client transports.
+type clientTLS struct {
+	config   *tls.Config
+	certFile string
+	keyFile  string
+	rootCAs  *x509.CertPool
+}
+
+func (c *clientTLS) enabled() bool {
+	return c.config != nil || c.certFile != "" || c.rootCAs != nil
+}
+
+func (c *clientTLS) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.config != nil {
+		config = c.config.Clone()
+	}
+	if c.certFile != "" {
+		reloader, err := newCertReloader(c.certFile, c.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = reloader.GetClientCertificate
+	}
+	if c.rootCAs != nil {
+		config.RootCAs = c.rootCAs
+	}
+	return config, nil
+}
+
+// httpClient returns a copy of client using the TLS options, client itself when there are none
+func (c *clientTLS) httpClient(client *http.Client) (*http.Client, error) {
+	if !c.enabled() {
+		return client, nil
+	}
+
+	var transport *http.Transport
+	switch rt := client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = rt.Clone()
+	default:
+		return nil, fmt.Errorf("TLS options need an *http.Transport, the HTTP client has a %T", rt)
+	}
+
+	config, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = config
+
+	withTLS := *client
+	withTLS.Transport = transport
+	return &withTLS, nil
+}
+
+type clientCertificateKey struct{}
+
+// setClientCertificateToCtx passes the verified certificate of the client of r, if any, to the handlers
+func setClientCertificateToCtx(ctx context.Context, r *http.Request) context.Context {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, clientCertificateKey{}, r.TLS.VerifiedChains[0][0])
+}
+
+// GetClientCertificateFromCtx returns the certificate a client authenticated with over mutual TLS,
+// verified against the client CAs of the server transport.
+func GetClientCertificateFromCtx(ctx context.Context) (*x509.Certificate, error) {
+	cert, ok := ctx.Value(clientCertificateKey{}).(*x509.Certificate)
+	if !ok {
+		return nil, errors.New("no client certificate found")
+	}
+	return cert, nil
+}
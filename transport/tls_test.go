@@ -0,0 +1,217 @@
+package transport
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue writes a certificate for name signed by the CA and its key in dir, returning their files
+func (ca *testCA) issue(t *testing.T, dir, name string, usage x509.ExtKeyUsage) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey: %v", err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return certFile, keyFile
+}
+
+func TestStreamableHTTPMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, dir, "agent", x509.ExtKeyUsageClientAuth)
+
+	port, err := getAvailablePort()
+	if err != nil {
+		t.Fatalf("Failed to get available port: %v", err)
+	}
+	serverAddr := fmt.Sprintf("127.0.0.1:%d", port)
+	serverURL := fmt.Sprintf("https://%s/mcp", serverAddr)
+
+	svr := NewStreamableHTTPServerTransport(serverAddr,
+		WithStreamableHTTPServerTransportOptionTLS(serverCert, serverKey),
+		WithStreamableHTTPServerTransportOptionClientCAs(ca.pool))
+	svr.SetSessionManager(newMockSessionManager())
+	identities := make(chan string, 1)
+	svr.SetReceiver(ServerReceiverF(func(ctx context.Context, _ string, msg []byte) (<-chan []byte, error) {
+		cert, err := GetClientCertificateFromCtx(ctx)
+		if err != nil {
+			return nil, err
+		}
+		identities <- cert.Subject.CommonName
+		msgCh := make(chan []byte, 1)
+		msgCh <- msg
+		close(msgCh)
+		return msgCh, nil
+	}))
+	go func() {
+		_ = svr.Run()
+	}()
+	defer func() {
+		serverCtx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_ = svr.Shutdown(context.Background(), serverCtx)
+	}()
+	for i := 0; ; i++ {
+		conn, err := net.Dial("tcp", serverAddr)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if i == 100 {
+			t.Fatalf("server not started: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	client, err := NewStreamableHTTPClientTransport(serverURL,
+		WithStreamableHTTPClientOptionRootCAs(ca.pool),
+		WithStreamableHTTPClientOptionClientCertificate(clientCert, clientKey))
+	if err != nil {
+		t.Fatalf("NewStreamableHTTPClientTransport failed: %v", err)
+	}
+	received := make(chan string, 1)
+	client.SetReceiver(NewClientReceiver(func(_ context.Context, msg []byte) error {
+		received <- string(msg)
+		return nil
+	}, func(error) {}))
+	if err = client.Start(); err != nil {
+		t.Fatalf("client.Start() failed: %v", err)
+	}
+	defer client.Close()
+
+	if err = client.Send(context.Background(), Message(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)); err != nil {
+		t.Fatalf("client.Send() failed: %v", err)
+	}
+	if identity := <-identities; identity != "agent" {
+		t.Errorf("client identity = %q, want agent", identity)
+	}
+	<-received
+
+	// without a client certificate the handshake fails
+	anonymous, err := NewStreamableHTTPClientTransport(serverURL, WithStreamableHTTPClientOptionRootCAs(ca.pool))
+	if err != nil {
+		t.Fatalf("NewStreamableHTTPClientTransport failed: %v", err)
+	}
+	if err = anonymous.Send(context.Background(), Message(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)); err == nil {
+		t.Errorf("client.Send() without certificate succeeded")
+	}
+}
+
+func Test_certReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("newCertReloader: %v", err)
+	}
+	first, err := reloader.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate: %v", err)
+	}
+
+	// a renewal replaces the files
+	renewedDir := t.TempDir()
+	renewedCert, renewedKey := ca.issue(t, renewedDir, "server", x509.ExtKeyUsageServerAuth)
+	for from, to := range map[string]string{renewedCert: certFile, renewedKey: keyFile} {
+		if err = os.Rename(from, to); err != nil {
+			t.Fatalf("Rename: %v", err)
+		}
+		later := time.Now().Add(time.Minute)
+		if err = os.Chtimes(to, later, later); err != nil {
+			t.Fatalf("Chtimes: %v", err)
+		}
+	}
+
+	renewed, err := reloader.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate: %v", err)
+	}
+	if string(renewed.Certificate[0]) == string(first.Certificate[0]) {
+		t.Errorf("certificate not reloaded after the files changed")
+	}
+
+	// a broken renewal keeps the current certificate
+	if err = os.WriteFile(certFile, []byte("broken"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	muchLater := time.Now().Add(time.Hour)
+	if err = os.Chtimes(certFile, muchLater, muchLater); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+	current, err := reloader.GetCertificate(nil)
+	if err != nil || current != renewed {
+		t.Errorf("GetCertificate() = %v, %v, want the renewed certificate", current, err)
+	}
+}
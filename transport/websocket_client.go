@@ -0,0 +1,235 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+)
+
+type WebSocketClientTransportOption func(*webSocketClientTransport)
+
+func WithWebSocketClientOptionLogger(log pkg.Logger) WebSocketClientTransportOption {
+	return func(t *webSocketClientTransport) {
+		t.logger = log
+	}
+}
+
+// WithWebSocketClientOptionHeader adds headers to the handshake request, eg. for authorization.
+func WithWebSocketClientOptionHeader(header http.Header) WebSocketClientTransportOption {
+	return func(t *webSocketClientTransport) {
+		t.header = header
+	}
+}
+
+// WithWebSocketClientOptionSubprotocols sets the subprotocols offered to the server, "mcp" by default.
+// The server must select one of them, none offered accepts a server selecting none.
+func WithWebSocketClientOptionSubprotocols(subprotocols ...string) WebSocketClientTransportOption {
+	return func(t *webSocketClientTransport) {
+		t.subprotocols = subprotocols
+	}
+}
+
+// WithWebSocketClientOptionPingInterval sets how often the connection is pinged and how long the client
+// waits for the server to answer before giving up on the connection. An interval of 0 disables keepalive.
+func WithWebSocketClientOptionPingInterval(interval, pongTimeout time.Duration) WebSocketClientTransportOption {
+	return func(t *webSocketClientTransport) {
+		t.pingInterval = interval
+		t.pongTimeout = pongTimeout
+	}
+}
+
+// WithWebSocketClientOptionMaxMessageSize limits the size of a received message, the connection
+// is closed with status 1009 on a larger one. 16 MiB by default, 0 for no limit.
+func WithWebSocketClientOptionMaxMessageSize(size int64) WebSocketClientTransportOption {
+	return func(t *webSocketClientTransport) {
+		t.maxMessageSize = size
+	}
+}
+
+func WithWebSocketClientOptionTLSConfig(config *tls.Config) WebSocketClientTransportOption {
+	return func(t *webSocketClientTransport) {
+		t.tls.config = config
+	}
+}
+
+// WithWebSocketClientOptionClientCertificate authenticates to servers requiring mutual TLS with the certificate and key
+// of the given files, loaded again whenever they change.
+func WithWebSocketClientOptionClientCertificate(certFile, keyFile string) WebSocketClientTransportOption {
+	return func(t *webSocketClientTransport) {
+		t.tls.certFile = certFile
+		t.tls.keyFile = keyFile
+	}
+}
+
+// WithWebSocketClientOptionRootCAs sets the authorities server certificates are verified against, the system ones by default.
+func WithWebSocketClientOptionRootCAs(pool *x509.CertPool) WebSocketClientTransportOption {
+	return func(t *webSocketClientTransport) {
+		t.tls.rootCAs = pool
+	}
+}
+
+func WithWebSocketClientOptionDialTimeout(timeout time.Duration) WebSocketClientTransportOption {
+	return func(t *webSocketClientTransport) {
+		t.dialTimeout = timeout
+	}
+}
+
+func WithWebSocketClientOptionReceiveTimeout(timeout time.Duration) WebSocketClientTransportOption {
+	return func(t *webSocketClientTransport) {
+		t.receiveTimeout = timeout
+	}
+}
+
+type webSocketClientTransport struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	serverURL *url.URL
+
+	conn     *wsConn
+	receiver clientReceiver
+
+	// options
+	logger         pkg.Logger
+	header         http.Header
+	subprotocols   []string
+	pingInterval   time.Duration
+	pongTimeout    time.Duration
+	maxMessageSize int64
+	tls            clientTLS
+	tlsConfig      *tls.Config
+	dialTimeout    time.Duration
+	receiveTimeout time.Duration
+
+	readClose chan struct{}
+}
+
+// NewWebSocketClientTransport returns a transport connecting to a ws:// or wss:// server url.
+func NewWebSocketClientTransport(serverURL string, opts ...WebSocketClientTransportOption) (ClientTransport, error) {
+	parsedURL, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse server URL: %w", err)
+	}
+
+	t := &webSocketClientTransport{
+		serverURL:      parsedURL,
+		logger:         pkg.DefaultLogger,
+		subprotocols:   []string{defaultWebSocketSubprotocol},
+		pingInterval:   defaultWebSocketPingInterval,
+		pongTimeout:    defaultWebSocketPongTimeout,
+		maxMessageSize: defaultMaxMessageSize,
+		dialTimeout:    10 * time.Second,
+		receiveTimeout: time.Second * 30,
+		readClose:      make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if t.tls.enabled() {
+		if t.tlsConfig, err = t.tls.tlsConfig(); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+func (t *webSocketClientTransport) Start() error {
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+
+	dialCtx, cancel := context.WithTimeout(t.ctx, t.dialTimeout)
+	defer cancel()
+
+	conn, subprotocol, err := wsDial(dialCtx, t.serverURL, t.header, t.subprotocols, t.tlsConfig)
+	if err != nil {
+		t.cancel()
+		return fmt.Errorf("failed to connect to websocket: %w", err)
+	}
+	if t.pingInterval > 0 {
+		conn.readTimeout = t.pingInterval + t.pongTimeout
+	}
+	conn.maxMessageSize = t.maxMessageSize
+	t.conn = conn
+
+	t.logger.Debugf("websocket connected to %s, subprotocol=%q", t.serverURL.String(), subprotocol)
+
+	go func() {
+		defer pkg.Recover()
+		defer close(t.readClose)
+
+		t.readLoop()
+	}()
+
+	if t.pingInterval > 0 {
+		go func() {
+			defer pkg.Recover()
+
+			keepalive(t.ctx, conn, t.pingInterval)
+		}()
+	}
+
+	return nil
+}
+
+func (t *webSocketClientTransport) readLoop() {
+	for {
+		msg, err := t.conn.readMessage()
+		if err != nil {
+			select {
+			case <-t.ctx.Done():
+				return
+			default:
+			}
+			t.logger.Errorf("websocket read: %+v", err)
+			t.receiver.Interrupt(fmt.Errorf("websocket connection disconnection: %w", err))
+			return
+		}
+
+		t.logger.Debugf("Received message: %s", string(msg))
+
+		ctx, cancel := context.WithTimeout(t.ctx, t.receiveTimeout)
+		if err = t.receiver.Receive(ctx, msg); err != nil {
+			t.logger.Errorf("Error receive message: %v", err)
+		}
+		cancel()
+	}
+}
+
+func (t *webSocketClientTransport) Send(ctx context.Context, msg Message) error {
+	t.logger.Debugf("Sending message: %s", msg)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.ctx.Done():
+		return t.ctx.Err()
+	default:
+	}
+
+	if err := t.conn.writeMessage(msg); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return nil
+}
+
+func (t *webSocketClientTransport) SetReceiver(receiver clientReceiver) {
+	t.receiver = receiver
+}
+
+func (t *webSocketClientTransport) Close() error {
+	t.cancel()
+
+	_ = t.conn.close(wsCloseNormal, "")
+
+	<-t.readClose
+
+	return nil
+}
@@ -0,0 +1,466 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // required by the WebSocket handshake
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// A minimal RFC 6455 implementation: the handshake for both sides, text and binary messages
+// (fragmented or not), ping/pong and the close handshake. Extensions are not negotiated.
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+
+	wsCloseNormal        = 1000
+	wsCloseGoingAway     = 1001
+	wsCloseProtocolError = 1002
+	wsCloseInvalidData   = 1007
+	wsCloseTooBig        = 1009
+
+	wsAcceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+var errWebSocketClosed = errors.New("websocket closed")
+
+type wsConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// client connections mask the frames they send, server connections expect masked frames
+	client bool
+
+	// readTimeout, when set, closes a connection that receives no frame for so long, pings keep it alive
+	readTimeout time.Duration
+	// maxMessageSize limits the size of a received message, 0 for no limit
+	maxMessageSize int64
+
+	writeMu sync.Mutex
+
+	closeOnce sync.Once
+}
+
+func newWSConn(conn net.Conn, reader *bufio.Reader, client bool) *wsConn {
+	if reader == nil {
+		reader = bufio.NewReader(conn)
+	}
+	return &wsConn{conn: conn, reader: reader, client: client}
+}
+
+// readMessage returns the next text or binary message, answering pings and the close handshake on the way.
+// It returns io.EOF once the peer has closed the connection.
+func (c *wsConn) readMessage() ([]byte, error) {
+	var (
+		message []byte
+		started bool
+		text    bool
+	)
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case wsOpPing:
+			if err = c.writeFrame(wsOpPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			code := wsCloseNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			_ = c.close(code, "")
+			return nil, io.EOF
+		case wsOpText, wsOpBinary:
+			if started {
+				return nil, c.fail(wsCloseProtocolError, "new message inside a fragmented message")
+			}
+			started = true
+			text = opcode == wsOpText
+			message = payload
+		case wsOpContinuation:
+			if !started {
+				return nil, c.fail(wsCloseProtocolError, "continuation frame without a message")
+			}
+			message = append(message, payload...)
+		default:
+			return nil, c.fail(wsCloseProtocolError, fmt.Sprintf("unknown opcode %d", opcode))
+		}
+
+		if c.maxMessageSize > 0 && int64(len(message)) > c.maxMessageSize {
+			return nil, c.fail(wsCloseTooBig, fmt.Sprintf("message exceeds %d bytes", c.maxMessageSize))
+		}
+		if fin {
+			if text && !utf8.Valid(message) {
+				return nil, c.fail(wsCloseInvalidData, "invalid UTF-8 in a text message")
+			}
+			return message, nil
+		}
+	}
+}
+
+func (c *wsConn) readFrame() (bool, byte, []byte, error) {
+	if c.readTimeout > 0 {
+		if err := c.conn.SetReadDeadline(time.Now().Add(c.readTimeout)); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(wsCloseProtocolError, "reserved bits set")
+	}
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	if masked == c.client {
+		return false, 0, nil, c.fail(wsCloseProtocolError, "unexpected frame masking")
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if opcode >= wsOpClose && (length > 125 || !fin) {
+		return false, 0, nil, c.fail(wsCloseProtocolError, "invalid control frame")
+	}
+	if c.maxMessageSize > 0 && length > uint64(c.maxMessageSize) {
+		return false, 0, nil, c.fail(wsCloseTooBig, fmt.Sprintf("message exceeds %d bytes", c.maxMessageSize))
+	}
+	if length > 1<<40 {
+		return false, 0, nil, c.fail(wsCloseTooBig, "frame too large")
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload, err := readAnnounced(c.reader, int64(length))
+	if err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		maskBytes(mask, payload)
+	}
+	return fin, opcode, payload, nil
+}
+
+func (c *wsConn) writeMessage(data []byte) error {
+	return c.writeFrame(wsOpText, data)
+}
+
+func (c *wsConn) ping() error {
+	return c.writeFrame(wsOpPing, nil)
+}
+
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+14)
+	frame = append(frame, 0x80|opcode)
+
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch length := len(payload); {
+	case length <= 125:
+		frame = append(frame, maskBit|byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, maskBit|126, byte(length>>8), byte(length))
+	default:
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(length))
+		frame = append(frame, maskBit|127)
+		frame = append(frame, ext[:]...)
+	}
+
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		maskBytes(mask, frame[start:])
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// close sends a close frame and closes the connection, the peer's answer is not awaited
+func (c *wsConn) close(code int, reason string) error {
+	err := errWebSocketClosed
+	c.closeOnce.Do(func() {
+		payload := make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		payload = append(payload, reason...)
+		_ = c.writeFrame(wsOpClose, payload)
+		err = c.conn.Close()
+	})
+	return err
+}
+
+// fail closes the connection after a protocol violation of the peer
+func (c *wsConn) fail(code int, reason string) error {
+	_ = c.close(code, reason)
+	return fmt.Errorf("websocket protocol error: %s", reason)
+}
+
+func maskBytes(mask [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= mask[i%4]
+	}
+}
+
+func wsAcceptKey(key string) string {
+	h := sha1.New() //nolint:gosec
+	h.Write([]byte(key + wsAcceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func headerTokens(header http.Header, name string) []string {
+	var tokens []string
+	for _, value := range header.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tokens = append(tokens, t)
+			}
+		}
+	}
+	return tokens
+}
+
+// wsUpgrade completes the server side handshake. The first subprotocol offered by the client that the server supports
+// is selected, a client offering only unsupported ones is refused. It writes the error response itself.
+func wsUpgrade(w http.ResponseWriter, r *http.Request, subprotocols []string) (*wsConn, string, error) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, "", errors.New("websocket upgrade: method not GET")
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") || !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "Expected a WebSocket upgrade", http.StatusUpgradeRequired)
+		return nil, "", errors.New("websocket upgrade: missing upgrade headers")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Unsupported WebSocket version", http.StatusBadRequest)
+		return nil, "", errors.New("websocket upgrade: unsupported version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "Missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, "", errors.New("websocket upgrade: missing key")
+	}
+
+	var subprotocol string
+	if offered := headerTokens(r.Header, "Sec-WebSocket-Protocol"); len(offered) > 0 {
+	selection:
+		for _, o := range offered {
+			for _, s := range subprotocols {
+				if o == s {
+					subprotocol = s
+					break selection
+				}
+			}
+		}
+		if subprotocol == "" {
+			http.Error(w, "Unsupported WebSocket subprotocol", http.StatusBadRequest)
+			return nil, "", fmt.Errorf("websocket upgrade: unsupported subprotocols %v", offered)
+		}
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+		return nil, "", errors.New("websocket upgrade: response writer cannot be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, "", fmt.Errorf("websocket upgrade: hijack: %w", err)
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + wsAcceptKey(key) + "\r\n"
+	if subprotocol != "" {
+		response += "Sec-WebSocket-Protocol: " + subprotocol + "\r\n"
+	}
+	response += "\r\n"
+	if _, err = conn.Write([]byte(response)); err != nil {
+		_ = conn.Close()
+		return nil, "", fmt.Errorf("websocket upgrade: write response: %w", err)
+	}
+	// deadlines set by the http server no longer apply
+	_ = conn.SetDeadline(time.Time{})
+
+	return newWSConn(conn, rw.Reader, false), subprotocol, nil
+}
+
+// wsDial connects to a ws:// or wss:// url and completes the client side handshake.
+func wsDial(ctx context.Context, u *url.URL, header http.Header, subprotocols []string, tlsConfig *tls.Config) (*wsConn, string, error) {
+	var (
+		useTLS bool
+		port   string
+	)
+	switch u.Scheme {
+	case "ws", "http":
+		port = "80"
+	case "wss", "https":
+		useTLS, port = true, "443"
+	default:
+		return nil, "", fmt.Errorf("unsupported websocket url scheme %q", u.Scheme)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, "", err
+	}
+	if useTLS {
+		config := &tls.Config{MinVersion: tls.VersionTLS12}
+		if tlsConfig != nil {
+			config = tlsConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = u.Hostname()
+		}
+		tlsConn := tls.Client(conn, config)
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, "", err
+		}
+		conn = tlsConn
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	var nonce [16]byte
+	if _, err = rand.Read(nonce[:]); err != nil {
+		_ = conn.Close()
+		return nil, "", err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+
+	request := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Sec-WebSocket-Key", key)
+	request.Header.Set("Sec-WebSocket-Version", "13")
+	if len(subprotocols) > 0 {
+		request.Header.Set("Sec-WebSocket-Protocol", strings.Join(subprotocols, ", "))
+	}
+	if err = request.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, "", err
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		_ = conn.Close()
+		return nil, "", err
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		_ = conn.Close()
+		return nil, "", fmt.Errorf("websocket handshake: unexpected status %s", response.Status)
+	}
+	if !headerContainsToken(response.Header, "Upgrade", "websocket") ||
+		response.Header.Get("Sec-WebSocket-Accept") != wsAcceptKey(key) {
+		_ = conn.Close()
+		return nil, "", errors.New("websocket handshake: invalid upgrade response")
+	}
+
+	subprotocol := response.Header.Get("Sec-WebSocket-Protocol")
+	if subprotocol != "" {
+		found := false
+		for _, s := range subprotocols {
+			found = found || s == subprotocol
+		}
+		if !found {
+			_ = conn.Close()
+			return nil, "", fmt.Errorf("websocket handshake: server selected unoffered subprotocol %q", subprotocol)
+		}
+	} else if len(subprotocols) > 0 {
+		_ = conn.Close()
+		return nil, "", fmt.Errorf("websocket handshake: server accepted none of the subprotocols %v", subprotocols)
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+	return newWSConn(conn, reader, true), subprotocol, nil
+}
@@ -0,0 +1,428 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ThinkInAIXYZ/go-mcp/pkg"
+)
+
+const (
+	// defaultWebSocketSubprotocol is offered by clients and accepted by servers unless configured otherwise
+	defaultWebSocketSubprotocol = "mcp"
+
+	defaultWebSocketPingInterval = 30 * time.Second
+	defaultWebSocketPongTimeout  = 10 * time.Second
+)
+
+type WebSocketServerTransportOption func(*webSocketServerTransport)
+
+func WithWebSocketServerTransportOptionLogger(logger pkg.Logger) WebSocketServerTransportOption {
+	return func(t *webSocketServerTransport) {
+		t.logger = logger
+	}
+}
+
+// WithWebSocketServerTransportOptionPath sets the path the connections are upgraded on, "/ws" by default.
+func WithWebSocketServerTransportOptionPath(path string) WebSocketServerTransportOption {
+	return func(t *webSocketServerTransport) {
+		t.path = path
+	}
+}
+
+// WithWebSocketServerTransportOptionSubprotocols sets the subprotocols the server accepts, in order of preference
+// of the client. Clients offering none of them are refused, clients offering no subprotocol at all are accepted.
+func WithWebSocketServerTransportOptionSubprotocols(subprotocols ...string) WebSocketServerTransportOption {
+	return func(t *webSocketServerTransport) {
+		t.subprotocols = subprotocols
+	}
+}
+
+// WithWebSocketServerTransportOptionPingInterval sets how often connections are pinged and how long the server
+// waits for the client to answer before closing the connection. An interval of 0 disables keepalive.
+func WithWebSocketServerTransportOptionPingInterval(interval, pongTimeout time.Duration) WebSocketServerTransportOption {
+	return func(t *webSocketServerTransport) {
+		t.pingInterval = interval
+		t.pongTimeout = pongTimeout
+	}
+}
+
+// WithWebSocketServerTransportOptionMaxMessageSize limits the size of a received message, the connection
+// is closed with status 1009 on a larger one. 16 MiB by default, 0 for no limit.
+func WithWebSocketServerTransportOptionMaxMessageSize(size int64) WebSocketServerTransportOption {
+	return func(t *webSocketServerTransport) {
+		t.maxMessageSize = size
+	}
+}
+
+// WithWebSocketServerTransportOptionTLS serves over TLS with the certificate and key of the given files,
+// loaded again whenever they change so that renewed certificates are used without a restart.
+func WithWebSocketServerTransportOptionTLS(certFile, keyFile string) WebSocketServerTransportOption {
+	return func(t *webSocketServerTransport) {
+		t.tls.certFile = certFile
+		t.tls.keyFile = keyFile
+	}
+}
+
+// WithWebSocketServerTransportOptionTLSConfig serves over TLS with config, completed by the other TLS options.
+func WithWebSocketServerTransportOptionTLSConfig(config *tls.Config) WebSocketServerTransportOption {
+	return func(t *webSocketServerTransport) {
+		t.tls.config = config
+	}
+}
+
+// WithWebSocketServerTransportOptionClientCAs requires clients to authenticate with a certificate issued by one of pool,
+// handlers get it with GetClientCertificateFromCtx.
+func WithWebSocketServerTransportOptionClientCAs(pool *x509.CertPool) WebSocketServerTransportOption {
+	return func(t *webSocketServerTransport) {
+		t.tls.clientCAs = pool
+	}
+}
+
+// WithWebSocketServerTransportOptionAllowedOrigins sets the origins, as "https://app.example.com", browsers may
+// connect from besides the server's own, "*" for any. Loopback origins are allowed too when the server is bound
+// to a loopback address. Browsers don't apply CORS to WebSocket handshakes, the server refuses other origins itself.
+func WithWebSocketServerTransportOptionAllowedOrigins(origins ...string) WebSocketServerTransportOption {
+	return func(t *webSocketServerTransport) {
+		t.originGuard.allowedOrigins = origins
+	}
+}
+
+// WithWebSocketServerTransportOptionAllowedHosts sets the Host values, with or without port, the server answers to.
+// By default any Host is accepted, but a loopback name only when the server is bound to a loopback address.
+func WithWebSocketServerTransportOptionAllowedHosts(hosts ...string) WebSocketServerTransportOption {
+	return func(t *webSocketServerTransport) {
+		t.originGuard.allowedHosts = hosts
+	}
+}
+
+type WebSocketServerTransportAndHandlerOption func(*webSocketServerTransport)
+
+func WithWebSocketServerTransportAndHandlerOptionLogger(logger pkg.Logger) WebSocketServerTransportAndHandlerOption {
+	return func(t *webSocketServerTransport) {
+		t.logger = logger
+	}
+}
+
+func WithWebSocketServerTransportAndHandlerOptionSubprotocols(subprotocols ...string) WebSocketServerTransportAndHandlerOption {
+	return func(t *webSocketServerTransport) {
+		t.subprotocols = subprotocols
+	}
+}
+
+func WithWebSocketServerTransportAndHandlerOptionPingInterval(interval, pongTimeout time.Duration) WebSocketServerTransportAndHandlerOption {
+	return func(t *webSocketServerTransport) {
+		t.pingInterval = interval
+		t.pongTimeout = pongTimeout
+	}
+}
+
+func WithWebSocketServerTransportAndHandlerOptionMaxMessageSize(size int64) WebSocketServerTransportAndHandlerOption {
+	return func(t *webSocketServerTransport) {
+		t.maxMessageSize = size
+	}
+}
+
+func WithWebSocketServerTransportAndHandlerOptionAllowedOrigins(origins ...string) WebSocketServerTransportAndHandlerOption {
+	return func(t *webSocketServerTransport) {
+		t.originGuard.allowedOrigins = origins
+	}
+}
+
+func WithWebSocketServerTransportAndHandlerOptionAllowedHosts(hosts ...string) WebSocketServerTransportAndHandlerOption {
+	return func(t *webSocketServerTransport) {
+		t.originGuard.allowedHosts = hosts
+	}
+}
+
+type webSocketServerTransport struct {
+	// ctx is the context that controls the lifecycle of the server
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	httpSvr *http.Server
+
+	inFlySend sync.WaitGroup
+
+	receiver serverReceiver
+
+	sessionManager sessionManager
+
+	// options
+	logger         pkg.Logger
+	path           string
+	subprotocols   []string
+	pingInterval   time.Duration
+	pongTimeout    time.Duration
+	maxMessageSize int64
+	tls            serverTLS
+	originGuard    originGuard
+}
+
+type WebSocketHandler struct {
+	transport *webSocketServerTransport
+}
+
+// HandleWebSocket upgrades incoming connections to WebSocket, each connection is a session.
+func (h *WebSocketHandler) HandleWebSocket() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.transport.handleWebSocket(w, r)
+	})
+}
+
+// NewWebSocketServerTransport returns transport that will start an HTTP server
+func NewWebSocketServerTransport(addr string, opts ...WebSocketServerTransportOption) ServerTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t := &webSocketServerTransport{
+		ctx:            ctx,
+		cancel:         cancel,
+		logger:         pkg.DefaultLogger,
+		path:           "/ws",
+		subprotocols:   []string{defaultWebSocketSubprotocol},
+		pingInterval:   defaultWebSocketPingInterval,
+		pongTimeout:    defaultWebSocketPongTimeout,
+		maxMessageSize: defaultMaxMessageSize,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(t.path, t.handleWebSocket)
+
+	t.httpSvr = &http.Server{
+		Addr:        addr,
+		Handler:     mux,
+		IdleTimeout: time.Minute,
+	}
+
+	return t
+}
+
+// NewWebSocketServerTransportAndHandler returns transport without starting the HTTP server,
+// and returns a Handler for users to start their own HTTP server externally
+// eg:
+// transport, handler, _ := NewWebSocketServerTransportAndHandler()
+// http.Handle("/ws", handler.HandleWebSocket())
+// http.ListenAndServe(":8080", nil)
+func NewWebSocketServerTransportAndHandler(
+	opts ...WebSocketServerTransportAndHandlerOption,
+) (ServerTransport, *WebSocketHandler, error) { //nolint:whitespace
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t := &webSocketServerTransport{
+		ctx:            ctx,
+		cancel:         cancel,
+		logger:         pkg.DefaultLogger,
+		subprotocols:   []string{defaultWebSocketSubprotocol},
+		pingInterval:   defaultWebSocketPingInterval,
+		pongTimeout:    defaultWebSocketPongTimeout,
+		maxMessageSize: defaultMaxMessageSize,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t, &WebSocketHandler{transport: t}, nil
+}
+
+func (t *webSocketServerTransport) Run() error {
+	if t.httpSvr == nil {
+		<-t.ctx.Done()
+		return nil
+	}
+
+	fmt.Printf("starting mcp server at %s://%s%s\n", t.tls.scheme("ws"), t.httpSvr.Addr, t.path)
+
+	if err := t.tls.listenAndServe(t.httpSvr); err != nil {
+		return fmt.Errorf("failed to start HTTP server: %w", err)
+	}
+	return nil
+}
+
+func (t *webSocketServerTransport) Send(ctx context.Context, sessionID string, msg Message) error {
+	t.inFlySend.Add(1)
+	defer t.inFlySend.Done()
+
+	select {
+	case <-t.ctx.Done():
+		return t.ctx.Err()
+	default:
+		return t.sessionManager.EnqueueMessageForSend(ctx, sessionID, msg)
+	}
+}
+
+func (t *webSocketServerTransport) SetReceiver(receiver serverReceiver) {
+	t.receiver = receiver
+}
+
+func (t *webSocketServerTransport) SetSessionManager(manager sessionManager) {
+	t.sessionManager = manager
+}
+
+// handleWebSocket serves one connection: messages read from it are handed to the receiver,
+// messages queued for its session are written to it.
+func (t *webSocketServerTransport) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	defer pkg.Recover()
+
+	select {
+	case <-t.ctx.Done():
+		http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+		return
+	default:
+	}
+
+	// the handshake is not subject to CORS, cross-site WebSocket hijacking is stopped here
+	if err := t.originGuard.check(w, r); err != nil {
+		t.logger.Errorf("webSocketServerTransport refuse connection: %v", err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	conn, subprotocol, err := wsUpgrade(w, r, t.subprotocols)
+	if err != nil {
+		t.logger.Errorf("webSocketServerTransport upgrade fail: %v", err)
+		return
+	}
+	if t.pingInterval > 0 {
+		conn.readTimeout = t.pingInterval + t.pongTimeout
+	}
+	conn.maxMessageSize = t.maxMessageSize
+
+	sessionID := t.sessionManager.CreateSession(r.Context())
+	defer t.sessionManager.CloseSession(sessionID)
+
+	t.logger.Debugf("websocket connected, sessionID=%s, subprotocol=%q", sessionID, subprotocol)
+
+	if err = t.sessionManager.OpenMessageQueueForSend(sessionID); err != nil {
+		t.logger.Errorf("handleWebSocket sessionID=%s OpenMessageQueueForSend fail: %v", sessionID, err)
+		_ = conn.close(wsCloseGoingAway, "")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(setClientCertificateToCtx(r.Context(), r))
+	defer cancel()
+
+	writeDone := make(chan struct{})
+	go func() {
+		defer pkg.Recover()
+		defer close(writeDone)
+
+		t.writeLoop(ctx, conn, sessionID)
+	}()
+
+	if t.pingInterval > 0 {
+		go func() {
+			defer pkg.Recover()
+
+			keepalive(ctx, conn, t.pingInterval)
+		}()
+	}
+
+	for {
+		msg, err := conn.readMessage()
+		if err != nil {
+			t.logger.Debugf("websocket read end: %v, sessionID=%s", err, sessionID)
+			break
+		}
+
+		t.logger.Debugf("Received message: %s", string(msg))
+
+		outputMsgCh, err := t.receiver.Receive(ctx, sessionID, msg)
+		if err != nil {
+			t.logger.Errorf("Failed to receive: %v, sessionID=%s", err, sessionID)
+			continue
+		}
+		if outputMsgCh == nil {
+			continue
+		}
+
+		go func() {
+			defer pkg.Recover()
+
+			for msg := range outputMsgCh {
+				if e := t.Send(context.Background(), sessionID, msg); e != nil {
+					t.logger.Errorf("Failed to send message: %v", e)
+				}
+			}
+		}()
+	}
+
+	cancel()
+	_ = conn.close(wsCloseNormal, "")
+	<-writeDone
+}
+
+func (t *webSocketServerTransport) writeLoop(ctx context.Context, conn *wsConn, sessionID string) {
+	for {
+		msg, err := t.sessionManager.DequeueMessageForSend(ctx, sessionID)
+		if err != nil {
+			if errors.Is(err, pkg.ErrSendEOF) {
+				// the session was closed, by shutdown most likely
+				_ = conn.close(wsCloseGoingAway, "")
+				return
+			}
+			t.logger.Debugf("websocket dequeueMessage err: %+v, sessionID=%s", err.Error(), sessionID)
+			return
+		}
+
+		t.logger.Debugf("Sending message: %s", string(msg))
+
+		if err = conn.writeMessage(msg); err != nil {
+			t.logger.Errorf("Failed to write message: %v", err)
+			_ = conn.close(wsCloseGoingAway, "")
+			return
+		}
+	}
+}
+
+// keepalive pings conn every interval until ctx is done, the peer's answers extend the read deadline of conn
+func keepalive(ctx context.Context, conn *wsConn, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := conn.ping(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (t *webSocketServerTransport) Shutdown(userCtx context.Context, serverCtx context.Context) error {
+	shutdownFunc := func() {
+		<-serverCtx.Done()
+
+		t.cancel()
+
+		t.inFlySend.Wait()
+
+		// closing the sessions ends the write loops, which close the connections
+		t.sessionManager.CloseAllSessions()
+	}
+
+	if t.httpSvr == nil {
+		shutdownFunc()
+		return nil
+	}
+
+	t.httpSvr.RegisterOnShutdown(shutdownFunc)
+
+	if err := t.httpSvr.Shutdown(userCtx); err != nil {
+		return fmt.Errorf("failed to shutdown HTTP server: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebSocket(t *testing.T) {
+	port, err := getAvailablePort()
+	if err != nil {
+		t.Fatalf("Failed to get available port: %v", err)
+	}
+
+	serverAddr := fmt.Sprintf("127.0.0.1:%d", port)
+	serverURL := fmt.Sprintf("ws://%s/ws", serverAddr)
+
+	svr := NewWebSocketServerTransport(serverAddr)
+
+	client, err := NewWebSocketClientTransport(serverURL)
+	if err != nil {
+		t.Fatalf("NewWebSocketClientTransport failed: %v", err)
+	}
+
+	testTransport(t, client, svr)
+}
+
+func TestWebSocketHandler(t *testing.T) {
+	port, err := getAvailablePort()
+	if err != nil {
+		t.Fatalf("Failed to get available port: %v", err)
+	}
+
+	svr, handler, err := NewWebSocketServerTransportAndHandler()
+	if err != nil {
+		t.Fatalf("NewWebSocketServerTransportAndHandler failed: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/mcp/ws", handler.HandleWebSocket())
+	go func() {
+		if e := http.ListenAndServe(fmt.Sprintf("127.0.0.1:%d", port), mux); e != nil {
+			log.Fatalf("Failed to start HTTP server: %v", e)
+		}
+	}()
+
+	client, err := NewWebSocketClientTransport(fmt.Sprintf("ws://127.0.0.1:%d/mcp/ws", port))
+	if err != nil {
+		t.Fatalf("NewWebSocketClientTransport failed: %v", err)
+	}
+
+	testTransport(t, client, svr)
+}
+
+func TestWebSocketSubprotocol(t *testing.T) {
+	port, err := getAvailablePort()
+	if err != nil {
+		t.Fatalf("Failed to get available port: %v", err)
+	}
+
+	serverAddr := fmt.Sprintf("127.0.0.1:%d", port)
+	svr := NewWebSocketServerTransport(serverAddr, WithWebSocketServerTransportOptionSubprotocols("mcp.v2", "mcp"))
+	svr.SetSessionManager(newMockSessionManager())
+	go func() {
+		_ = svr.Run()
+	}()
+	defer func() {
+		serverCtx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_ = svr.Shutdown(context.Background(), serverCtx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	u, _ := url.Parse(fmt.Sprintf("ws://%s/ws", serverAddr))
+
+	conn, subprotocol, err := wsDial(context.Background(), u, nil, []string{"mcp", "mcp.v2"}, nil)
+	if err != nil {
+		t.Fatalf("wsDial failed: %v", err)
+	}
+	_ = conn.close(wsCloseNormal, "")
+	if subprotocol != "mcp" {
+		t.Fatalf("subprotocol = %q, want the client's first supported one %q", subprotocol, "mcp")
+	}
+
+	if _, _, err = wsDial(context.Background(), u, nil, []string{"graphql-ws"}, nil); err == nil ||
+		!strings.Contains(err.Error(), "400") {
+		t.Fatalf("wsDial with an unsupported subprotocol: err = %v, want a refused handshake", err)
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	port, err := getAvailablePort()
+	if err != nil {
+		t.Fatalf("Failed to get available port: %v", err)
+	}
+
+	serverAddr := fmt.Sprintf("127.0.0.1:%d", port)
+	svr := NewWebSocketServerTransport(serverAddr)
+	svr.SetSessionManager(newMockSessionManager())
+	svr.SetReceiver(ServerReceiverF(func(context.Context, string, []byte) (<-chan []byte, error) {
+		return nil, nil
+	}))
+	go func() {
+		_ = svr.Run()
+	}()
+	defer func() {
+		serverCtx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_ = svr.Shutdown(context.Background(), serverCtx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	u, _ := url.Parse(fmt.Sprintf("ws://%s/ws", serverAddr))
+
+	// a page of another site can't open a connection to a local server
+	foreign := http.Header{"Origin": []string{"https://attacker.example"}}
+	if _, _, err = wsDial(context.Background(), u, foreign, nil, nil); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("wsDial from a foreign origin: err = %v, want a forbidden handshake", err)
+	}
+
+	local := http.Header{"Origin": []string{"http://localhost:5173"}}
+	conn, _, err := wsDial(context.Background(), u, local, nil, nil)
+	if err != nil {
+		t.Fatalf("wsDial from a loopback origin failed: %v", err)
+	}
+	_ = conn.close(wsCloseNormal, "")
+}
+
+func Test_wsConn(t *testing.T) {
+	clientSide, serverSide := net.Pipe()
+	client := newWSConn(clientSide, nil, true)
+	server := newWSConn(serverSide, nil, false)
+	defer client.close(wsCloseNormal, "")
+
+	large := bytes.Repeat([]byte("x"), 70000)
+	go func() {
+		_ = client.ping()
+		_ = client.writeMessage(large)
+	}()
+
+	// the ping is answered while reading the message
+	pong := make(chan error, 1)
+	go func() {
+		fin, opcode, _, err := client.readFrame()
+		if err == nil && (!fin || opcode != wsOpPong) {
+			err = fmt.Errorf("got opcode %d, want a pong", opcode)
+		}
+		pong <- err
+	}()
+
+	msg, err := server.readMessage()
+	if err != nil {
+		t.Fatalf("readMessage failed: %v", err)
+	}
+	if !bytes.Equal(msg, large) {
+		t.Fatalf("readMessage got %d bytes, want %d", len(msg), len(large))
+	}
+	if err = <-pong; err != nil {
+		t.Fatalf("pong: %v", err)
+	}
+
+	server.maxMessageSize = 10
+	go func() {
+		_ = client.writeMessage([]byte("more than ten bytes"))
+		// take the close frame the server answers with
+		_, _ = client.readMessage()
+	}()
+	if _, err = server.readMessage(); err == nil {
+		t.Fatalf("readMessage of a message over the limit: want error")
+	}
+}
+
+func Test_wsConnFrameLength(t *testing.T) {
+	clientSide, serverSide := net.Pipe()
+	client := newWSConn(clientSide, nil, true)
+	client.maxMessageSize = defaultMaxMessageSize
+	defer client.close(wsCloseNormal, "")
+
+	go func() {
+		// a frame announcing 512 GiB, the close frame the client answers with is discarded
+		header := []byte{0x80 | wsOpBinary, 127, 0, 0, 0, 0x80, 0, 0, 0, 0}
+		_, _ = serverSide.Write(header)
+		_, _ = io.Copy(io.Discard, serverSide)
+	}()
+
+	_, _, _, err := client.readFrame()
+	if err == nil || !strings.Contains(err.Error(), "message exceeds") {
+		t.Fatalf("readFrame of an oversized frame: err = %v, want the limit exceeded", err)
+	}
+}
+
+func Test_wsConnFrameLengthWithoutLimit(t *testing.T) {
+	clientSide, serverSide := net.Pipe()
+	client := newWSConn(clientSide, nil, true)
+	defer client.close(wsCloseNormal, "")
+
+	go func() {
+		// a frame announcing 512 GiB followed by a few bytes only
+		header := []byte{0x80 | wsOpBinary, 127, 0, 0, 0, 0x80, 0, 0, 0, 0}
+		_, _ = serverSide.Write(append(header, "{}"...))
+		_ = serverSide.Close()
+	}()
+
+	if _, _, _, err := client.readFrame(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("readFrame of a truncated frame: err = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func Test_wsConnInvalidUTF8(t *testing.T) {
+	clientSide, serverSide := net.Pipe()
+	client := newWSConn(clientSide, nil, true)
+	server := newWSConn(serverSide, nil, false)
+	defer server.close(wsCloseNormal, "")
+
+	closeCode := make(chan int, 1)
+	go func() {
+		_ = client.writeFrame(wsOpText, []byte{'{', 0xff, '}'})
+		// the close frame the server answers with
+		_, opcode, payload, err := client.readFrame()
+		if err != nil || opcode != wsOpClose || len(payload) < 2 {
+			closeCode <- 0
+			return
+		}
+		closeCode <- int(binary.BigEndian.Uint16(payload))
+	}()
+
+	if _, err := server.readMessage(); err == nil {
+		t.Fatalf("readMessage of invalid UTF-8 text: want error")
+	}
+	if code := <-closeCode; code != wsCloseInvalidData {
+		t.Errorf("close code = %d, want %d", code, wsCloseInvalidData)
+	}
+}